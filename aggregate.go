@@ -0,0 +1,189 @@
+package dbo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nzai/log"
+)
+
+// Number numeric types supported by aggregate functions
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+// Sum sum of column
+func Sum[T any, N Number](ctx context.Context, condition QueryCondition, column string) (N, error) {
+	db, err := GetDB(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return SumTx[T, N](ctx, db, condition, column)
+}
+
+// SumTx sum of column with db context
+func SumTx[T any, N Number](ctx context.Context, db *DBContext, condition QueryCondition, column string) (N, error) {
+	return aggregateTx[T, N](ctx, db, condition, OpSum, column)
+}
+
+// Avg average of column
+func Avg[T any](ctx context.Context, condition QueryCondition, column string) (float64, error) {
+	db, err := GetDB(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return AvgTx[T](ctx, db, condition, column)
+}
+
+// AvgTx average of column with db context
+func AvgTx[T any](ctx context.Context, db *DBContext, condition QueryCondition, column string) (float64, error) {
+	return aggregateTx[T, float64](ctx, db, condition, OpAvg, column)
+}
+
+// Max max value of column
+func Max[T any, V any](ctx context.Context, condition QueryCondition, column string) (V, error) {
+	db, err := GetDB(ctx)
+	if err != nil {
+		var value V
+		return value, err
+	}
+
+	return MaxTx[T, V](ctx, db, condition, column)
+}
+
+// MaxTx max value of column with db context
+func MaxTx[T any, V any](ctx context.Context, db *DBContext, condition QueryCondition, column string) (V, error) {
+	return aggregateTx[T, V](ctx, db, condition, OpMax, column)
+}
+
+// Min min value of column
+func Min[T any, V any](ctx context.Context, condition QueryCondition, column string) (V, error) {
+	db, err := GetDB(ctx)
+	if err != nil {
+		var value V
+		return value, err
+	}
+
+	return MinTx[T, V](ctx, db, condition, column)
+}
+
+// MinTx min value of column with db context
+func MinTx[T any, V any](ctx context.Context, db *DBContext, condition QueryCondition, column string) (V, error) {
+	return aggregateTx[T, V](ctx, db, condition, OpMin, column)
+}
+
+// aggregateTx select function(column) from table of T, zero value will be returned if there is no matched rows
+func aggregateTx[T any, V any](ctx context.Context, db *DBContext, condition QueryCondition, function, column string) (V, error) {
+	db.ResetCondition()
+
+	wheres, parameters := condition.GetConditions()
+	if len(wheres) > 0 {
+		db.DB = db.Where(strings.Join(wheres, " and "), parameters...)
+	}
+	db.scopeTenant(new(T))
+
+	var entity T
+	var result sql.Null[V]
+	tableName := db.GetTableName(entity)
+	op := db.beginOperation(ctx, function, tableName)
+	err := db.Table(tableName).Select(fmt.Sprintf("%s(%s)", function, column)).Row().Scan(&result)
+	if err != nil {
+		err = op.finish(0, err)
+		log.Warn(ctx, function+" failed",
+			log.Err(err),
+			log.String("tableName", tableName),
+			log.String("column", column),
+			log.Any("condition", db.maskCondition(new(T), condition)),
+			log.Duration("duration", time.Since(op.start)))
+		return result.V, err
+	}
+
+	op.finish(1, nil)
+	log.Debug(ctx, function+" successfully",
+		log.String("tableName", tableName),
+		log.String("column", column),
+		log.Any("condition", db.maskCondition(new(T), condition)),
+		log.Any("result", result.V),
+		log.Duration("duration", time.Since(op.start)))
+
+	return result.V, nil
+}
+
+// GroupBy group rows of T by groupColumns and scan the aggregates into R.
+// aggregates are select expressions such as "sum(amount) as total", having is optional
+func GroupBy[T any, R any](ctx context.Context, condition QueryCondition, groupColumns []string, aggregates []string, having QueryCondition) ([]R, error) {
+	db, err := GetDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return GroupByTx[T, R](ctx, db, condition, groupColumns, aggregates, having)
+}
+
+// GroupByTx group rows of T by groupColumns with db context
+func GroupByTx[T any, R any](ctx context.Context, db *DBContext, condition QueryCondition, groupColumns []string, aggregates []string, having QueryCondition) ([]R, error) {
+	db.ResetCondition()
+
+	wheres, parameters := condition.GetConditions()
+	if len(wheres) > 0 {
+		db.DB = db.Where(strings.Join(wheres, " and "), parameters...)
+	}
+	db.scopeTenant(new(T))
+
+	var entity T
+	tableName := db.GetTableName(entity)
+	selects := make([]string, 0, len(groupColumns)+len(aggregates))
+	selects = append(selects, groupColumns...)
+	selects = append(selects, aggregates...)
+	db.DB = db.Table(tableName).Select(strings.Join(selects, ", "))
+
+	if len(groupColumns) > 0 {
+		db.DB = db.Group(strings.Join(groupColumns, ", "))
+	}
+
+	if having != nil {
+		havings, parameters := having.GetConditions()
+		if len(havings) > 0 {
+			db.DB = db.Having(strings.Join(havings, " and "), parameters...)
+		}
+	}
+
+	orderBy, ok := condition.(OrderByCondition)
+	if ok {
+		db.DB = db.Order(orderBy.GetOrderBy())
+	}
+
+	op := db.beginOperation(ctx, OpGroupBy, tableName)
+	values := make([]R, 0)
+	err := db.Scan(&values).Error
+	if err != nil {
+		err = op.finish(0, err)
+		log.Warn(ctx, "group by failed",
+			log.Err(err),
+			log.String("tableName", tableName),
+			log.Strings("groupColumns", groupColumns),
+			log.Strings("aggregates", aggregates),
+			log.Any("condition", db.maskCondition(new(T), condition)),
+			log.Any("having", db.maskCondition(new(T), having)),
+			log.Duration("duration", time.Since(op.start)))
+		return nil, err
+	}
+
+	op.finish(int64(len(values)), nil)
+	log.Debug(ctx, "group by successfully",
+		log.String("tableName", tableName),
+		log.Strings("groupColumns", groupColumns),
+		log.Strings("aggregates", aggregates),
+		log.Any("condition", db.maskCondition(new(T), condition)),
+		log.Any("having", db.maskCondition(new(T), having)),
+		log.Duration("duration", time.Since(op.start)))
+
+	return values, nil
+}
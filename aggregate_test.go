@@ -0,0 +1,195 @@
+package dbo
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// rowsConnector connector of connections returning the same rows to every query, queries are recorded
+// so that statements can be checked without database
+type rowsConnector struct {
+	columns []string
+	rows    [][]driver.Value
+	queries *[]string
+}
+
+func (c rowsConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return rowsConn(c), nil
+}
+
+func (c rowsConnector) Driver() driver.Driver {
+	return nil
+}
+
+type rowsConn rowsConnector
+
+func (c rowsConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	*c.queries = append(*c.queries, query)
+	return &driverRows{columns: c.columns, rows: c.rows}, nil
+}
+
+func (c rowsConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c rowsConn) Close() error {
+	return nil
+}
+
+func (c rowsConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+type driverRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *driverRows) Columns() []string {
+	return r.columns
+}
+
+func (r *driverRows) Close() error {
+	return nil
+}
+
+func (r *driverRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// rowsDB get db context of dbo executing queries on connector
+func rowsDB(ctx context.Context, dbo *DBO, connector rowsConnector) *DBContext {
+	db := dbo.db.Session(&gorm.Session{Context: ctx, NewDB: true, SkipDefaultTransaction: true})
+	db.Statement.ConnPool = sql.OpenDB(connector)
+	return &DBContext{DB: db, dbo: dbo}
+}
+
+func TestAggregate(t *testing.T) {
+	ctx := context.Background()
+	dbo := newTestDBO(t, getDefaultConfig())
+
+	tests := []struct {
+		name      string
+		aggregate func(db *DBContext) (any, error)
+		rows      [][]driver.Value
+		want      any
+		wantSQL   string
+	}{
+		{
+			name: "sum",
+			aggregate: func(db *DBContext) (any, error) {
+				return SumTx[tableA, int64](ctx, db, nameCondition("a"), "id")
+			},
+			rows:    [][]driver.Value{{int64(42)}},
+			want:    int64(42),
+			wantSQL: "SELECT sum(id) FROM `table_a` WHERE name = ?",
+		},
+		{
+			name: "sum of no rows",
+			aggregate: func(db *DBContext) (any, error) {
+				return SumTx[tableA, int64](ctx, db, nameCondition("a"), "id")
+			},
+			rows:    [][]driver.Value{{nil}},
+			want:    int64(0),
+			wantSQL: "SELECT sum(id) FROM `table_a` WHERE name = ?",
+		},
+		{
+			name: "avg of no rows",
+			aggregate: func(db *DBContext) (any, error) {
+				return AvgTx[tableA](ctx, db, nameCondition("a"), "id")
+			},
+			rows:    [][]driver.Value{{nil}},
+			want:    float64(0),
+			wantSQL: "SELECT avg(id) FROM `table_a` WHERE name = ?",
+		},
+		{
+			name: "max",
+			aggregate: func(db *DBContext) (any, error) {
+				return MaxTx[tableA, string](ctx, db, nameCondition("a"), "name")
+			},
+			rows:    [][]driver.Value{{"z"}},
+			want:    "z",
+			wantSQL: "SELECT max(name) FROM `table_a` WHERE name = ?",
+		},
+		{
+			name: "min of no rows",
+			aggregate: func(db *DBContext) (any, error) {
+				return MinTx[tableA, string](ctx, db, nameCondition("a"), "name")
+			},
+			rows:    [][]driver.Value{{nil}},
+			want:    "",
+			wantSQL: "SELECT min(name) FROM `table_a` WHERE name = ?",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries := make([]string, 0)
+			db := rowsDB(ctx, dbo, rowsConnector{columns: []string{"value"}, rows: tt.rows, queries: &queries})
+
+			got, err := tt.aggregate(db)
+			if err != nil {
+				t.Fatalf("aggregate error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("aggregate = %v, want %v", got, tt.want)
+			}
+
+			if len(queries) != 1 || queries[0] != tt.wantSQL {
+				t.Errorf("queries = %q, want %q", queries, tt.wantSQL)
+			}
+		})
+	}
+}
+
+type nameTotal struct {
+	Name  string
+	Total int64
+}
+
+type havingCondition int64
+
+func (c havingCondition) GetConditions() ([]string, []any) {
+	return []string{"total > ?"}, []any{int64(c)}
+}
+
+func TestGroupBy(t *testing.T) {
+	ctx := context.Background()
+	dbo := newTestDBO(t, getDefaultConfig())
+
+	queries := make([]string, 0)
+	db := rowsDB(ctx, dbo, rowsConnector{
+		columns: []string{"name", "total"},
+		rows:    [][]driver.Value{{"a", int64(2)}, {"b", int64(3)}},
+		queries: &queries,
+	})
+
+	values, err := GroupByTx[tableA, nameTotal](ctx, db, nameCondition("a"), []string{"name"}, []string{"count(*) as total"}, havingCondition(1))
+	if err != nil {
+		t.Fatalf("GroupByTx() error = %v", err)
+	}
+
+	want := []nameTotal{{Name: "a", Total: 2}, {Name: "b", Total: 3}}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("GroupByTx() = %v, want %v", values, want)
+	}
+
+	wantSQL := "SELECT name, count(*) as total FROM `table_a` WHERE name = ? GROUP BY `name` HAVING total > ?"
+	if len(queries) != 1 || queries[0] != wantSQL {
+		t.Errorf("queries = %q, want %q", queries, wantSQL)
+	}
+}
@@ -2,15 +2,19 @@ package dbo
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"reflect"
 	"strings"
 	"time"
 
-	"github.com/go-sql-driver/mysql"
 	"github.com/nzai/log"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// getManyChunkSize max ids count of a single IN clause in GetMany
+const getManyChunkSize = 500
+
 func Insert[T any](ctx context.Context, value T) (int64, error) {
 	db, err := GetDB(ctx)
 	if err != nil {
@@ -21,31 +25,23 @@ func Insert[T any](ctx context.Context, value T) (int64, error) {
 }
 
 func InsertTx[T any](ctx context.Context, db *DBContext, value T) (int64, error) {
-	start := time.Now()
+	op := db.beginOperation(ctx, OpInsert, db.GetTableName(value))
 	newDB := db.ResetCondition().Create(value)
 	if newDB.Error != nil {
-		me, ok := newDB.Error.(*mysql.MySQLError)
-		if ok && me.Number == 1062 {
-			log.Warn(ctx, "insert duplicate record",
-				log.Err(me),
-				log.String("tableName", db.GetTableName(value)),
-				log.Any("value", value),
-				log.Duration("duration", time.Since(start)))
-			return 0, ErrDuplicateRecord
-		}
-
+		err := op.finish(0, newDB.Error)
 		log.Warn(ctx, "insert failed",
-			log.Err(newDB.Error),
+			log.Err(err),
 			log.String("tableName", db.GetTableName(value)),
-			log.Any("value", value),
-			log.Duration("duration", time.Since(start)))
-		return 0, newDB.Error
+			log.Any("value", db.maskValue(value)),
+			log.Duration("duration", time.Since(op.start)))
+		return 0, err
 	}
 
+	op.finish(newDB.RowsAffected, nil)
 	log.Debug(ctx, "insert successfully",
 		log.String("tableName", db.GetTableName(value)),
-		log.Any("value", value),
-		log.Duration("duration", time.Since(start)))
+		log.Any("value", db.maskValue(value)),
+		log.Duration("duration", time.Since(op.start)))
 
 	return newDB.RowsAffected, nil
 }
@@ -62,31 +58,23 @@ func InsertInBatches[T any](ctx context.Context, value []T, batchSize int) (int6
 
 // InsertInBatchesTx Insert records in batch with context. visit https://gorm.io/docs/create.html for detail
 func InsertInBatchesTx[T any](ctx context.Context, db *DBContext, value []T, batchSize int) (int64, error) {
-	start := time.Now()
+	op := db.beginOperation(ctx, OpInsertBatches, db.GetTableName(value))
 	newDB := db.ResetCondition().CreateInBatches(value, batchSize)
 	if newDB.Error != nil {
-		me, ok := newDB.Error.(*mysql.MySQLError)
-		if ok && me.Number == 1062 {
-			log.Warn(ctx, "insertBatches duplicate record",
-				log.Err(me),
-				log.String("tableName", db.GetTableName(value)),
-				log.Any("value", value),
-				log.Duration("duration", time.Since(start)))
-			return 0, ErrDuplicateRecord
-		}
-
+		err := op.finish(0, newDB.Error)
 		log.Warn(ctx, "insertBatches failed",
-			log.Err(newDB.Error),
+			log.Err(err),
 			log.String("tableName", db.GetTableName(value)),
-			log.Any("value", value),
-			log.Duration("duration", time.Since(start)))
-		return 0, newDB.Error
+			log.Any("value", db.maskValue(value)),
+			log.Duration("duration", time.Since(op.start)))
+		return 0, err
 	}
 
+	op.finish(newDB.RowsAffected, nil)
 	log.Debug(ctx, "insertBatches successfully",
 		log.String("tableName", db.GetTableName(value)),
-		log.Any("value", value),
-		log.Duration("duration", time.Since(start)))
+		log.Any("value", db.maskValue(value)),
+		log.Duration("duration", time.Since(op.start)))
 
 	return newDB.RowsAffected, nil
 }
@@ -101,31 +89,24 @@ func Update[T any](ctx context.Context, value T) (int64, error) {
 }
 
 func UpdateTx[T any](ctx context.Context, db *DBContext, value T) (int64, error) {
-	start := time.Now()
+	op := db.beginOperation(ctx, OpUpdate, db.GetTableName(value))
 	newDB := db.ResetCondition().Save(value)
 	if newDB.Error != nil {
-		me, ok := newDB.Error.(*mysql.MySQLError)
-		if ok && me.Number == 1062 {
-			log.Warn(ctx, "update duplicate record",
-				log.Err(me),
-				log.String("tableName", db.GetTableName(value)),
-				log.Any("value", value),
-				log.Duration("duration", time.Since(start)))
-			return 0, ErrDuplicateRecord
-		}
-
+		err := op.finish(0, newDB.Error)
 		log.Warn(ctx, "update failed",
-			log.Err(newDB.Error),
+			log.Err(err),
 			log.String("tableName", db.GetTableName(value)),
-			log.Any("value", value),
-			log.Duration("duration", time.Since(start)))
-		return 0, newDB.Error
+			log.Any("value", db.maskValue(value)),
+			log.Duration("duration", time.Since(op.start)))
+		return 0, err
 	}
 
+	db.invalidateEntityCache(ctx, value)
+	op.finish(newDB.RowsAffected, nil)
 	log.Debug(ctx, "update successfully",
 		log.String("tableName", db.GetTableName(value)),
-		log.Any("value", value),
-		log.Duration("duration", time.Since(start)))
+		log.Any("value", db.maskValue(value)),
+		log.Duration("duration", time.Since(op.start)))
 
 	return newDB.RowsAffected, nil
 }
@@ -140,25 +121,77 @@ func Save[T any](ctx context.Context, value T) error {
 }
 
 func SaveTx[T any](ctx context.Context, db *DBContext, value T) error {
-	start := time.Now()
-	err := db.ResetCondition().Save(value).Error
-	if err != nil {
+	op := db.beginOperation(ctx, OpSave, db.GetTableName(value))
+	newDB := db.ResetCondition().Save(value)
+	if newDB.Error != nil {
+		err := op.finish(0, newDB.Error)
 		log.Warn(ctx, "save failed",
 			log.Err(err),
 			log.String("tableName", db.GetTableName(value)),
-			log.Any("value", value),
-			log.Duration("duration", time.Since(start)))
+			log.Any("value", db.maskValue(value)),
+			log.Duration("duration", time.Since(op.start)))
 		return err
 	}
 
+	db.invalidateEntityCache(ctx, value)
+	op.finish(newDB.RowsAffected, nil)
 	log.Debug(ctx, "save successfully",
 		log.String("tableName", db.GetTableName(value)),
-		log.Any("value", value),
-		log.Duration("duration", time.Since(start)))
+		log.Any("value", db.maskValue(value)),
+		log.Duration("duration", time.Since(op.start)))
 
 	return nil
 }
 
+// Delete delete record by id, cached entity is invalidated
+func Delete[T any](ctx context.Context, id any) (int64, error) {
+	db, err := GetDB(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return DeleteTx[T](ctx, db, id)
+}
+
+// DeleteTx delete record by id with db context, cached entity is invalidated after commit in transactions
+func DeleteTx[T any](ctx context.Context, db *DBContext, id any) (int64, error) {
+	value := new(T)
+	tableName := db.GetTableName(value)
+	op := db.beginOperation(ctx, OpDelete, tableName)
+	field, err := db.getPrimaryField(value)
+	if err != nil {
+		err = op.finish(0, err)
+		log.Warn(ctx, "delete failed due to invalid primary key",
+			log.Err(err),
+			log.Any("id", id),
+			log.String("tableName", tableName))
+		return 0, err
+	}
+
+	newDB := db.ResetCondition().
+		Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: id}).
+		Delete(value)
+	if newDB.Error != nil {
+		err = op.finish(0, newDB.Error)
+		log.Warn(ctx, "delete failed",
+			log.Err(err),
+			log.Any("id", id),
+			log.String("tableName", tableName),
+			log.Duration("duration", time.Since(op.start)))
+		return 0, err
+	}
+
+	db.invalidateCache(ctx, tableName, []any{id})
+	op.finish(newDB.RowsAffected, nil)
+	log.Debug(ctx, "delete successfully",
+		log.Any("id", id),
+		log.String("tableName", tableName),
+		log.Int64("rowsAffected", newDB.RowsAffected),
+		log.Duration("duration", time.Since(op.start)))
+
+	return newDB.RowsAffected, nil
+}
+
 func Get[T any](ctx context.Context, id any) (value T, err error) {
 	db, err := GetDB(ctx)
 	if err != nil {
@@ -168,32 +201,212 @@ func Get[T any](ctx context.Context, id any) (value T, err error) {
 	return GetTx[T](ctx, db, id)
 }
 
-func GetTx[T any](ctx context.Context, db *DBContext, id any) (T, error) {
-	start := time.Now()
+// GetTx get record by id with db context, lock options are available in transaction only
+func GetTx[T any](ctx context.Context, db *DBContext, id any, options ...LockOption) (T, error) {
+	return getByKeysTx[T](ctx, db, []any{id}, options)
+}
+
+// GetByKey get record by primary keys, keys must be in the order of primary key columns
+func GetByKey[T any](ctx context.Context, keys ...any) (value T, err error) {
+	db, err := GetDB(ctx)
+	if err != nil {
+		return value, err
+	}
+
+	return GetByKeyTx[T](ctx, db, keys...)
+}
+
+// GetByKeyTx get record by primary keys with db context
+func GetByKeyTx[T any](ctx context.Context, db *DBContext, keys ...any) (T, error) {
+	return getByKeysTx[T](ctx, db, keys, nil)
+}
+
+func getByKeysTx[T any](ctx context.Context, db *DBContext, keys []any, options []LockOption) (T, error) {
 	value := new(T)
 
-	err := db.ResetCondition().Where("id=?", id).First(value).Error
+	// invalid keys and lock options are observed as failed operations
+	op := db.beginOperation(ctx, OpGet, db.GetTableName(value))
+	fields, err := db.getPrimaryFields(value)
+	if err == nil && len(fields) != len(keys) {
+		err = fmt.Errorf("%w: table %s expects %d key(s) %v, got %d",
+			ErrInvalidPrimaryKey, db.GetTableName(value), len(fields), fieldNames(fields), len(keys))
+	}
+	if err != nil {
+		err = op.finish(0, err)
+		log.Warn(ctx, "get by id failed due to invalid primary key",
+			log.Err(err),
+			log.Any("id", keys),
+			log.String("tableName", db.GetTableName(value)))
+		return *value, err
+	}
+
+	db.ResetCondition()
+	err = db.applyLockOptions(options)
+	if err != nil {
+		err = op.finish(0, err)
+		log.Warn(ctx, "get by id failed due to invalid lock options",
+			log.Err(err),
+			log.Any("id", keys),
+			log.String("tableName", db.GetTableName(value)))
+		return *value, err
+	}
+
+	for index, field := range fields {
+		db.DB = db.Where(clause.Eq{
+			Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName},
+			Value:  keys[index],
+		})
+	}
+
+	if db.canReadCache(value, options) {
+		err = db.dbo.cache.load(ctx, db.dbo.cache.key(db.GetTableName(value), keys), value, func() error {
+			return db.First(value).Error
+		})
+		// cached entities are shared by tenants
+		if err == nil {
+			var ok bool
+			ok, err = db.inTenant(ctx, value)
+			if err == nil && !ok {
+				*value = *new(T)
+				err = gorm.ErrRecordNotFound
+			}
+		}
+	} else {
+		err = db.First(value).Error
+	}
 	if err == nil {
+		op.finish(1, nil)
 		log.Debug(ctx, "get by id successfully",
-			log.Any("id", id),
+			log.Any("id", keys),
 			log.String("tableName", db.GetTableName(value)),
-			log.Any("value", value),
-			log.Duration("duration", time.Since(start)))
+			log.Any("value", db.maskValue(value)),
+			log.Duration("duration", time.Since(op.start)))
 		return *value, nil
 	}
 
+	err = op.finish(0, err)
 	log.Warn(ctx, "get by id failed",
 		log.Err(err),
-		log.Any("id", id),
+		log.Any("id", keys),
 		log.String("tableName", db.GetTableName(value)),
-		log.Any("value", value),
-		log.Duration("duration", time.Since(start)))
+		log.Any("value", db.maskValue(value)),
+		log.Duration("duration", time.Since(op.start)))
 
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return *value, ErrRecordNotFound
+	return *value, err
+}
+
+// GetMany get records by primary key ids. values are returned in the order of ids,
+// ids without matched records are returned as missing
+func GetMany[T any](ctx context.Context, ids []any) ([]T, []any, error) {
+	db, err := GetDB(ctx)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return *value, err
+	return GetManyTx[T](ctx, db, ids)
+}
+
+// GetManyTx get records by primary key ids with db context
+func GetManyTx[T any](ctx context.Context, db *DBContext, ids []any) ([]T, []any, error) {
+	var entity T
+	tableName := db.GetTableName(entity)
+	op := db.beginOperation(ctx, OpGetMany, tableName)
+	field, err := db.getPrimaryField(entity)
+	if err != nil {
+		err = op.finish(0, err)
+		log.Warn(ctx, "get many failed due to invalid primary key",
+			log.Err(err),
+			log.String("tableName", tableName))
+		return nil, nil, err
+	}
+
+	// duplicated ids are queried only once, ids are converted to the type of primary key so that ids of
+	// different types, such as "01" and 1, match the same entity
+	keys := make([]string, len(ids))
+	uniqueIDs := make([]any, 0, len(ids))
+	seen := make(map[string]struct{}, len(ids))
+	for index, id := range ids {
+		id = primaryKeyValue(ctx, field, id)
+		keys[index] = fmt.Sprint(id)
+		if _, found := seen[keys[index]]; found {
+			continue
+		}
+
+		seen[keys[index]] = struct{}{}
+		uniqueIDs = append(uniqueIDs, id)
+	}
+
+	found := make(map[string]T, len(uniqueIDs))
+	readCache := db.canReadCache(new(T), nil)
+	var generation uint64
+	if readCache {
+		generation = db.dbo.cache.invalidations.Load()
+		uniqueIDs = getCachedEntities(ctx, db.dbo.cache, tableName, uniqueIDs, found)
+		err = removeOtherTenants(ctx, db, found)
+		if err != nil {
+			err = op.finish(0, err)
+			log.Warn(ctx, "get many failed",
+				log.Err(err),
+				log.String("tableName", tableName),
+				log.Int("ids", len(ids)),
+				log.Duration("duration", time.Since(op.start)))
+			return nil, nil, err
+		}
+	}
+
+	loaded := make(map[string]any)
+	for offset := 0; offset < len(uniqueIDs); offset += getManyChunkSize {
+		chunk := uniqueIDs[offset:min(offset+getManyChunkSize, len(uniqueIDs))]
+
+		values := make([]T, 0, len(chunk))
+		err = db.ResetCondition().
+			Where(clause.IN{Column: clause.Column{Name: field.DBName}, Values: chunk}).
+			Find(&values).Error
+		if err != nil {
+			err = op.finish(int64(len(found)), err)
+			log.Warn(ctx, "get many failed",
+				log.Err(err),
+				log.String("tableName", tableName),
+				log.Int("ids", len(ids)),
+				log.Int("offset", offset),
+				log.Duration("duration", time.Since(op.start)))
+			return nil, nil, err
+		}
+
+		for _, value := range values {
+			key, _ := field.ValueOf(ctx, reflect.ValueOf(value))
+			found[fmt.Sprint(key)] = value
+			if readCache {
+				loaded[db.dbo.cache.key(tableName, []any{key})] = value
+			}
+		}
+	}
+
+	if len(loaded) > 0 {
+		db.dbo.cache.set(ctx, generation, loaded)
+	}
+
+	values := make([]T, 0, len(ids))
+	missing := make([]any, 0)
+	for index, id := range ids {
+		value, ok := found[keys[index]]
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+
+		values = append(values, value)
+	}
+
+	op.finish(int64(len(values)), nil)
+	log.Debug(ctx, "get many successfully",
+		log.String("tableName", tableName),
+		log.Int("ids", len(ids)),
+		log.Int("found", len(values)),
+		log.Any("missing", missing),
+		log.Duration("duration", time.Since(op.start)))
+
+	return values, missing, nil
 }
 
 func Query[T any](ctx context.Context, condition QueryCondition) ([]T, error) {
@@ -205,9 +418,18 @@ func Query[T any](ctx context.Context, condition QueryCondition) ([]T, error) {
 	return QueryTx[T](ctx, db, condition)
 }
 
-func QueryTx[T any](ctx context.Context, db *DBContext, condition QueryCondition) ([]T, error) {
+// QueryTx query values with db context, lock options are available in transaction only
+func QueryTx[T any](ctx context.Context, db *DBContext, condition QueryCondition, options ...LockOption) ([]T, error) {
 	db.ResetCondition()
 
+	err := db.applyLockOptions(options)
+	if err != nil {
+		log.Warn(ctx, "query values failed due to invalid lock options",
+			log.Err(err),
+			log.Any("condition", db.maskCondition(new(T), condition)))
+		return nil, err
+	}
+
 	wheres, parameters := condition.GetConditions()
 	if len(wheres) > 0 {
 		db.DB = db.Where(strings.Join(wheres, " and "), parameters...)
@@ -228,35 +450,47 @@ func QueryTx[T any](ctx context.Context, db *DBContext, condition QueryCondition
 		}
 	}
 
-	start := time.Now()
 	values := make([]T, 0)
-	err := db.Find(&values).Error
+	op := db.beginOperation(ctx, OpQuery, db.GetTableName(values))
+	err = db.guard(ctx, OpQuery, db.GetTableName(values), len(wheres) > 0, func(tx *gorm.DB) *gorm.DB {
+		return tx.Find(&values)
+	})
+	if err == nil {
+		err = db.Find(&values).Error
+	}
 	if err != nil {
+		err = op.finish(0, err)
 		log.Warn(ctx, "query values failed",
 			log.Err(err),
 			log.String("tableName", db.GetTableName(values)),
-			log.Any("condition", condition),
-			log.Duration("duration", time.Since(start)))
+			log.Any("condition", db.maskCondition(new(T), condition)),
+			log.Duration("duration", time.Since(op.start)))
 		return nil, err
 	}
 
+	op.finish(int64(len(values)), nil)
 	log.Debug(ctx, "query values successfully",
 		log.String("tableName", db.GetTableName(values)),
-		log.Any("condition", condition),
-		log.Duration("duration", time.Since(start)))
+		log.Any("condition", db.maskCondition(new(T), condition)),
+		log.Duration("duration", time.Since(op.start)))
 
 	return values, nil
 }
 
 func QueryMap[T Entity](ctx context.Context, condition QueryCondition) (map[string]T, error) {
+	return QueryMapBy(ctx, condition, func(v T) string { return v.GetID() })
+}
+
+// QueryMapBy query values and map them by the key returned from keyFn
+func QueryMapBy[T any, K comparable](ctx context.Context, condition QueryCondition, keyFn func(T) K) (map[K]T, error) {
 	values, err := Query[T](ctx, condition)
 	if err != nil {
 		return nil, err
 	}
 
-	m := make(map[string]T, len(values))
+	m := make(map[K]T, len(values))
 	for _, v := range values {
-		m[v.GetID()] = v
+		m[keyFn(v)] = v
 	}
 
 	return m, nil
@@ -278,25 +512,33 @@ func CountTx[T any](ctx context.Context, db *DBContext, condition QueryCondition
 	if len(wheres) > 0 {
 		db.DB = db.Where(strings.Join(wheres, " and "), parameters...)
 	}
+	db.scopeTenant(new(T))
 
-	start := time.Now()
 	var total int64
 	var value T
 	tableName := db.GetTableName(value)
-	err := db.Table(tableName).Count(&total).Error
+	op := db.beginOperation(ctx, OpCount, tableName)
+	err := db.guard(ctx, OpCount, tableName, len(wheres) > 0, func(tx *gorm.DB) *gorm.DB {
+		return tx.Table(tableName).Count(&total)
+	})
+	if err == nil {
+		err = db.Table(tableName).Count(&total).Error
+	}
 	if err != nil {
+		err = op.finish(0, err)
 		log.Warn(ctx, "count failed",
 			log.Err(err),
 			log.String("tableName", tableName),
-			log.Any("condition", condition),
-			log.Duration("duration", time.Since(start)))
+			log.Any("condition", db.maskCondition(new(T), condition)),
+			log.Duration("duration", time.Since(op.start)))
 		return 0, err
 	}
 
+	op.finish(1, nil)
 	log.Debug(ctx, "count successfully",
 		log.String("tableName", tableName),
-		log.Any("condition", condition),
-		log.Duration("duration", time.Since(start)))
+		log.Any("condition", db.maskCondition(new(T), condition)),
+		log.Duration("duration", time.Since(op.start)))
 
 	return total, nil
 }
@@ -323,3 +565,159 @@ func PageTx[T any](ctx context.Context, db *DBContext, condition QueryCondition)
 
 	return total, values, nil
 }
+
+// Exists check whether any record matches the condition
+func Exists[T any](ctx context.Context, condition QueryCondition) (bool, error) {
+	db, err := GetDB(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return ExistsTx[T](ctx, db, condition)
+}
+
+// ExistsTx check whether any record matches the condition with db context
+func ExistsTx[T any](ctx context.Context, db *DBContext, condition QueryCondition) (bool, error) {
+	db.ResetCondition()
+
+	wheres, parameters := condition.GetConditions()
+	if len(wheres) > 0 {
+		db.DB = db.Where(strings.Join(wheres, " and "), parameters...)
+	}
+	db.scopeTenant(new(T))
+
+	var value T
+	var rows []int
+	tableName := db.GetTableName(value)
+	op := db.beginOperation(ctx, OpExists, tableName)
+	err := db.Table(tableName).Select("1").Limit(1).Scan(&rows).Error
+	if err != nil {
+		err = op.finish(0, err)
+		log.Warn(ctx, "exists failed",
+			log.Err(err),
+			log.String("tableName", tableName),
+			log.Any("condition", db.maskCondition(new(T), condition)),
+			log.Duration("duration", time.Since(op.start)))
+		return false, err
+	}
+
+	op.finish(int64(len(rows)), nil)
+	log.Debug(ctx, "exists successfully",
+		log.String("tableName", tableName),
+		log.Any("condition", db.maskCondition(new(T), condition)),
+		log.Bool("exists", len(rows) > 0),
+		log.Duration("duration", time.Since(op.start)))
+
+	return len(rows) > 0, nil
+}
+
+// First get the first record matches the condition, order by primary key unless condition is an OrderByCondition
+func First[T any](ctx context.Context, condition QueryCondition) (value T, err error) {
+	db, err := GetDB(ctx)
+	if err != nil {
+		return value, err
+	}
+
+	return FirstTx[T](ctx, db, condition)
+}
+
+// FirstTx get the first record matches the condition with db context
+func FirstTx[T any](ctx context.Context, db *DBContext, condition QueryCondition) (T, error) {
+	db.ResetCondition()
+
+	wheres, parameters := condition.GetConditions()
+	if len(wheres) > 0 {
+		db.DB = db.Where(strings.Join(wheres, " and "), parameters...)
+	}
+
+	value := new(T)
+	op := db.beginOperation(ctx, OpFirst, db.GetTableName(value))
+
+	var err error
+	orderBy, ok := condition.(OrderByCondition)
+	if ok {
+		err = db.Order(orderBy.GetOrderBy()).Take(value).Error
+	} else {
+		err = db.First(value).Error
+	}
+	if err == nil {
+		op.finish(1, nil)
+		log.Debug(ctx, "get first successfully",
+			log.String("tableName", db.GetTableName(value)),
+			log.Any("condition", db.maskCondition(new(T), condition)),
+			log.Any("value", db.maskValue(value)),
+			log.Duration("duration", time.Since(op.start)))
+		return *value, nil
+	}
+
+	err = op.finish(0, err)
+	log.Warn(ctx, "get first failed",
+		log.Err(err),
+		log.String("tableName", db.GetTableName(value)),
+		log.Any("condition", db.maskCondition(new(T), condition)),
+		log.Duration("duration", time.Since(op.start)))
+
+	return *value, err
+}
+
+// Pluck query a single column of records match the condition
+func Pluck[T any, V any](ctx context.Context, condition QueryCondition, column string) ([]V, error) {
+	db, err := GetDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return PluckTx[T, V](ctx, db, condition, column)
+}
+
+// PluckTx query a single column of records match the condition with db context
+func PluckTx[T any, V any](ctx context.Context, db *DBContext, condition QueryCondition, column string) ([]V, error) {
+	db.ResetCondition()
+
+	wheres, parameters := condition.GetConditions()
+	if len(wheres) > 0 {
+		db.DB = db.Where(strings.Join(wheres, " and "), parameters...)
+	}
+	db.scopeTenant(new(T))
+
+	orderBy, ok := condition.(OrderByCondition)
+	if ok {
+		db.DB = db.Order(orderBy.GetOrderBy())
+	}
+
+	pc, ok := condition.(PagerCondition)
+	if ok {
+		pager := pc.GetPager()
+		if pager != nil && pager.Enable() {
+			// pagination
+			offset, limit := pager.Offset()
+			db.DB = db.Offset(offset).Limit(limit)
+		}
+	}
+
+	var value T
+	values := make([]V, 0)
+	tableName := db.GetTableName(value)
+	op := db.beginOperation(ctx, OpPluck, tableName)
+	err := db.Table(tableName).Pluck(column, &values).Error
+	if err != nil {
+		err = op.finish(0, err)
+		log.Warn(ctx, "pluck failed",
+			log.Err(err),
+			log.String("tableName", tableName),
+			log.String("column", column),
+			log.Any("condition", db.maskCondition(new(T), condition)),
+			log.Duration("duration", time.Since(op.start)))
+		return nil, err
+	}
+
+	op.finish(int64(len(values)), nil)
+	log.Debug(ctx, "pluck successfully",
+		log.String("tableName", tableName),
+		log.String("column", column),
+		log.Any("condition", db.maskCondition(new(T), condition)),
+		log.Int("count", len(values)),
+		log.Duration("duration", time.Since(op.start)))
+
+	return values, nil
+}
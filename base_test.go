@@ -2,8 +2,13 @@ package dbo
 
 import (
 	"context"
+	"database/sql/driver"
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
+
+	"gorm.io/gorm"
 )
 
 type tableA struct {
@@ -38,3 +43,175 @@ func TestGet(t *testing.T) {
 		t.Errorf("Get() = %v, want %v", got1, want1)
 	}
 }
+
+// returnRows make queries of dry run sessions of dbo return rows of table_a built by rows from statements
+func returnRows(t *testing.T, dbo *DBO, rows func(stmt *gorm.Statement) []tableA) {
+	t.Helper()
+
+	err := dbo.db.Callback().Query().After("gorm:query").Register("test:rows", func(db *gorm.DB) {
+		if values, ok := db.Statement.Dest.(*[]tableA); ok {
+			*values = append(*values, rows(db.Statement)...)
+			db.RowsAffected = int64(len(*values))
+		}
+	})
+	if err != nil {
+		t.Fatalf("register callback failed due to %v", err)
+	}
+}
+
+func TestGetMany(t *testing.T) {
+	ctx := context.Background()
+	dbo := newTestDBO(t, getDefaultConfig())
+
+	// rows of queried ids except 7
+	chunks := make([]int, 0)
+	returnRows(t, dbo, func(stmt *gorm.Statement) []tableA {
+		chunks = append(chunks, len(stmt.Vars))
+		values := make([]tableA, 0, len(stmt.Vars))
+		for _, id := range stmt.Vars {
+			if id != int64(7) {
+				values = append(values, tableA{ID: id.(int64)})
+			}
+		}
+		return values
+	})
+
+	ids := make([]any, 0, 1204)
+	for id := 1; id <= 1200; id++ {
+		ids = append(ids, id)
+	}
+	// duplicated ids of other types
+	ids = append(ids, "01", uint(2), int64(3), " 1200")
+
+	values, missing, err := GetManyTx[tableA](ctx, dryRun(ctx, dbo), ids)
+	if err != nil {
+		t.Fatalf("GetManyTx() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(chunks, []int{500, 500, 200}) {
+		t.Errorf("chunks = %v, want 500 500 200", chunks)
+	}
+
+	if !reflect.DeepEqual(missing, []any{7}) {
+		t.Errorf("missing = %v, want [7]", missing)
+	}
+
+	if len(values) != 1203 || values[0].ID != 1 || values[1199].ID != 1 || values[1202].ID != 1200 {
+		t.Errorf("values = %d, want 1203 values in the order of ids", len(values))
+	}
+}
+
+func TestQueryMapBy(t *testing.T) {
+	ctx := context.Background()
+	dbo := newTestDBO(t, getDefaultConfig())
+	dbo.db = dbo.db.Session(&gorm.Session{DryRun: true, SkipDefaultTransaction: true})
+	close(dbo.ready)
+	returnRows(t, dbo, func(stmt *gorm.Statement) []tableA {
+		return []tableA{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}, {ID: 1, Name: "c"}}
+	})
+
+	global, _ := GetGlobal()
+	ReplaceGlobal(dbo)
+	defer ReplaceGlobal(global)
+
+	values, err := QueryMapBy(ctx, nameCondition("a"), func(value tableA) int64 {
+		return value.ID
+	})
+	if err != nil {
+		t.Fatalf("QueryMapBy() error = %v", err)
+	}
+
+	// the last value of duplicated keys is kept
+	want := map[int64]tableA{1: {ID: 1, Name: "c"}, 2: {ID: 2, Name: "b"}}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("QueryMapBy() = %v, want %v", values, want)
+	}
+}
+
+func TestExistsFirstPluck(t *testing.T) {
+	ctx := context.Background()
+	dbo := newTestDBO(t, getDefaultConfig())
+
+	queries := make([]string, 0)
+	exists, err := ExistsTx[tableA](ctx, rowsDB(ctx, dbo, rowsConnector{columns: []string{"1"}, rows: [][]driver.Value{{int64(1)}}, queries: &queries}), nameCondition("a"))
+	if err != nil || !exists {
+		t.Errorf("ExistsTx() = %v, %v, want true", exists, err)
+	}
+
+	exists, err = ExistsTx[tableA](ctx, rowsDB(ctx, dbo, rowsConnector{columns: []string{"1"}, queries: &queries}), nameCondition("a"))
+	if err != nil || exists {
+		t.Errorf("ExistsTx() of no rows = %v, %v, want false", exists, err)
+	}
+
+	columns := []string{"id", "name", "remark"}
+	first, err := FirstTx[tableA](ctx, rowsDB(ctx, dbo, rowsConnector{columns: columns, rows: [][]driver.Value{{int64(1), "a", ""}}, queries: &queries}), nameCondition("a"))
+	if err != nil || first.ID != 1 {
+		t.Errorf("FirstTx() = %v, %v, want entity 1", first, err)
+	}
+
+	_, err = FirstTx[tableA](ctx, rowsDB(ctx, dbo, rowsConnector{columns: columns, queries: &queries}), nameCondition("a"))
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("FirstTx() of no rows error = %v, want %v", err, ErrRecordNotFound)
+	}
+
+	names, err := PluckTx[tableA, string](ctx, rowsDB(ctx, dbo, rowsConnector{columns: []string{"name"}, rows: [][]driver.Value{{"a"}, {"b"}}, queries: &queries}), nameCondition("a"), "name")
+	if err != nil || !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Errorf("PluckTx() = %v, %v, want [a b]", names, err)
+	}
+
+	want := []string{
+		"SELECT 1 FROM `table_a` WHERE name = ? LIMIT ?",
+		"SELECT 1 FROM `table_a` WHERE name = ? LIMIT ?",
+		"SELECT `table_a`.`id`,`table_a`.`name`,`table_a`.`remark` FROM `table_a` WHERE name = ? ORDER BY `table_a`.`id` LIMIT ?",
+		"SELECT `table_a`.`id`,`table_a`.`name`,`table_a`.`remark` FROM `table_a` WHERE name = ? ORDER BY `table_a`.`id` LIMIT ?",
+		"SELECT `name` FROM `table_a` WHERE name = ?",
+	}
+	if !reflect.DeepEqual(queries, want) {
+		t.Errorf("queries = %q, want %q", queries, want)
+	}
+}
+
+type orderItem struct {
+	OrderID  int64  `gorm:"column:order_id;primaryKey"`
+	ItemID   string `gorm:"column:item_id;primaryKey"`
+	Quantity int64  `gorm:"column:quantity"`
+}
+
+func (orderItem) TableName() string {
+	return "order_item"
+}
+
+func TestGetByKey(t *testing.T) {
+	ctx := context.Background()
+	dbo := newTestDBO(t, getDefaultConfig())
+
+	queries := make([]string, 0)
+	db := rowsDB(ctx, dbo, rowsConnector{
+		columns: []string{"order_id", "item_id", "quantity"},
+		rows:    [][]driver.Value{{int64(1), "a", int64(3)}},
+		queries: &queries,
+	})
+
+	value, err := GetByKeyTx[orderItem](ctx, db, 1, "a")
+	if err != nil || value != (orderItem{OrderID: 1, ItemID: "a", Quantity: 3}) {
+		t.Errorf("GetByKeyTx() = %v, %v", value, err)
+	}
+
+	want := "SELECT `order_item`.`order_id`,`order_item`.`item_id`,`order_item`.`quantity` FROM `order_item` " +
+		"WHERE `order_item`.`order_id` = ? AND `order_item`.`item_id` = ? ORDER BY `order_item`.`order_id` LIMIT ?"
+	if len(queries) != 1 || queries[0] != want {
+		t.Errorf("queries = %q, want %q", queries, want)
+	}
+
+	// keys must match primary key columns
+	for _, keys := range [][]any{{1}, {1, "a", 2}} {
+		_, err = GetByKeyTx[orderItem](ctx, db, keys...)
+		if !errors.Is(err, ErrInvalidPrimaryKey) || !strings.Contains(err.Error(), "expects 2 key(s) [order_id item_id]") {
+			t.Errorf("GetByKeyTx(%v) error = %v, want %v", keys, err, ErrInvalidPrimaryKey)
+		}
+	}
+
+	if len(queries) != 1 {
+		t.Errorf("queries = %q, keys of wrong arity should not be queried", queries)
+	}
+}
@@ -0,0 +1,495 @@
+package dbo
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/nzai/log"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Cache cache of encoded entities, such as MemoryCache or a redis backend.
+// implementations must be safe for concurrent use
+type Cache interface {
+	// Get get values of keys, missing and expired keys are absent from the result
+	Get(ctx context.Context, keys ...string) (map[string][]byte, error)
+	// Set set values expiring after ttl
+	Set(ctx context.Context, values map[string][]byte, ttl time.Duration) error
+	// Delete delete keys, missing keys are ignored
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// MemoryCache in-memory Cache evicting the least recently used keys beyond capacity
+type MemoryCache struct {
+	capacity int
+	mutex    sync.Mutex
+	items    map[string]*list.Element
+	// lru most recently used first
+	lru *list.List
+}
+
+type memoryCacheItem struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCache create in-memory cache with at most capacity keys
+func NewMemoryCache(capacity int) *MemoryCache {
+	return &MemoryCache{
+		capacity: max(capacity, 1),
+		items:    make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// Get get values of keys
+func (c *MemoryCache) Get(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	values := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		element, ok := c.items[key]
+		if !ok {
+			continue
+		}
+
+		item := element.Value.(*memoryCacheItem)
+		if now.After(item.expires) {
+			c.remove(element)
+			continue
+		}
+
+		c.lru.MoveToFront(element)
+		values[key] = item.value
+	}
+
+	return values, nil
+}
+
+// Set set values expiring after ttl
+func (c *MemoryCache) Set(ctx context.Context, values map[string][]byte, ttl time.Duration) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	expires := time.Now().Add(ttl)
+	for key, value := range values {
+		element, ok := c.items[key]
+		if ok {
+			item := element.Value.(*memoryCacheItem)
+			item.value, item.expires = value, expires
+			c.lru.MoveToFront(element)
+			continue
+		}
+
+		c.items[key] = c.lru.PushFront(&memoryCacheItem{key: key, value: value, expires: expires})
+		if c.lru.Len() > c.capacity {
+			c.remove(c.lru.Back())
+		}
+	}
+
+	return nil
+}
+
+// Delete delete keys
+func (c *MemoryCache) Delete(ctx context.Context, keys ...string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, key := range keys {
+		element, ok := c.items[key]
+		if ok {
+			c.remove(element)
+		}
+	}
+
+	return nil
+}
+
+// Len get count of keys, including expired ones not evicted yet
+func (c *MemoryCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.lru.Len()
+}
+
+func (c *MemoryCache) remove(element *list.Element) {
+	c.lru.Remove(element)
+	delete(c.items, element.Value.(*memoryCacheItem).key)
+}
+
+// entityCache read-through cache of entities keyed by database, table and primary key, encoded by gob
+type entityCache struct {
+	cache Cache
+	ttl   time.Duration
+	// namespace address and name of database, so that dbo of tenants sharing a cache never read each other
+	namespace string
+	group     singleflight.Group
+	// invalidations count of invalidations, entities loaded across an invalidation are not cached
+	invalidations atomic.Uint64
+}
+
+// newEntityCache create entity cache of database of dsn, nil if cache is not configured
+func newEntityCache(config *Config, dsn string) *entityCache {
+	if config.Cache == nil {
+		return nil
+	}
+
+	namespace := config.Database
+	mc, err := mysql.ParseDSN(dsn)
+	if err == nil {
+		namespace = mc.Addr + "/" + mc.DBName
+	}
+
+	return &entityCache{cache: config.Cache, ttl: config.CacheTTL, namespace: namespace}
+}
+
+// key key of entity in table with primary keys
+func (c *entityCache) key(table string, keys []any) string {
+	var builder strings.Builder
+	builder.WriteString("dbo:")
+	builder.WriteString(c.namespace)
+	builder.WriteString(":")
+	builder.WriteString(table)
+	for _, key := range keys {
+		builder.WriteString(":")
+		builder.WriteString(fmt.Sprint(key))
+	}
+
+	return builder.String()
+}
+
+// get get cached values of keys, cache failures are logged and treated as missing
+func (c *entityCache) get(ctx context.Context, keys ...string) map[string][]byte {
+	values, err := c.cache.Get(ctx, keys...)
+	if err != nil {
+		log.Warn(ctx, "get cached entities failed", log.Err(err), log.Strings("keys", keys))
+		return nil
+	}
+
+	return values
+}
+
+// set encode and cache entities by key, loaded when invalidations was generation
+func (c *entityCache) set(ctx context.Context, generation uint64, entities map[string]any) {
+	values := make(map[string][]byte, len(entities))
+	for key, entity := range entities {
+		value, err := encodeEntity(entity)
+		if err != nil {
+			log.Warn(ctx, "encode entity failed", log.Err(err), log.String("key", key))
+			continue
+		}
+		values[key] = value
+	}
+
+	if len(values) == 0 {
+		return
+	}
+
+	c.store(ctx, generation, values)
+}
+
+// store cache values loaded when invalidations was generation. values loaded before an invalidation may be stale,
+// they are skipped, or deleted again if the invalidation happens while caching them
+func (c *entityCache) store(ctx context.Context, generation uint64, values map[string][]byte) {
+	if c.invalidations.Load() != generation {
+		return
+	}
+
+	err := c.cache.Set(ctx, values, c.ttl)
+	if err != nil {
+		log.Warn(ctx, "cache entities failed", log.Err(err), log.Int("entities", len(values)))
+		return
+	}
+
+	if c.invalidations.Load() == generation {
+		return
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	err = c.cache.Delete(ctx, keys...)
+	if err != nil {
+		log.Warn(ctx, "delete stale cached entities failed", log.Err(err), log.Strings("keys", keys))
+	}
+}
+
+// invalidate delete cached entities of keys
+func (c *entityCache) invalidate(ctx context.Context, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	c.invalidations.Add(1)
+	err := c.cache.Delete(ctx, keys...)
+	if err != nil {
+		log.Warn(ctx, "invalidate cached entities failed", log.Err(err), log.Strings("keys", keys))
+	}
+}
+
+// load decode cached entity of key into dest, or fill dest by query and cache it.
+// concurrent loads of the same key share a single query
+func (c *entityCache) load(ctx context.Context, key string, dest any, query func() error) error {
+	value, ok := c.get(ctx, key)[key]
+	if ok && decodeEntity(value, dest) == nil {
+		return nil
+	}
+
+	leader := false
+	shared, err, _ := c.group.Do(key, func() (any, error) {
+		leader = true
+		generation := c.invalidations.Load()
+		err := query()
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := encodeEntity(dest)
+		if err != nil {
+			log.Warn(ctx, "encode entity failed", log.Err(err), log.String("key", key))
+			return nil, nil
+		}
+
+		c.store(ctx, generation, map[string][]byte{key: value})
+		return value, nil
+	})
+	if leader {
+		return err
+	}
+
+	// not found may depend on the scope of the leader, such as tenant
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return query()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// entity of the leader can not be encoded, query again
+	if shared == nil {
+		return query()
+	}
+
+	return decodeEntity(shared.([]byte), dest)
+}
+
+// cacheableTypes whether entities of type survive gob encoding, by reflect.Type
+var cacheableTypes sync.Map
+
+// cacheable check whether entities of rt can be cached. gob omits struct fields of pointers to zero values,
+// which are decoded as nil, so that entities with pointer fields, such as *bool of nullable columns, are never cached
+func cacheable(rt reflect.Type) bool {
+	for rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+
+	if ok, found := cacheableTypes.Load(rt); found {
+		return ok.(bool)
+	}
+
+	ok := !hasPointerField(rt, make(map[reflect.Type]bool))
+	cacheableTypes.Store(rt, ok)
+	return ok
+}
+
+// hasPointerField check whether rt or its nested structs have exported pointer fields
+func hasPointerField(rt reflect.Type, visited map[reflect.Type]bool) bool {
+	if visited[rt] {
+		return false
+	}
+	visited[rt] = true
+
+	switch rt.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return hasPointerField(rt.Elem(), visited)
+	case reflect.Map:
+		return hasPointerField(rt.Key(), visited) || hasPointerField(rt.Elem(), visited)
+	case reflect.Struct:
+		// types encoding themselves, such as time.Time, are encoded as a whole
+		pt := reflect.PointerTo(rt)
+		if pt.Implements(gobEncoderType) || pt.Implements(binaryMarshalerType) {
+			return false
+		}
+
+		for index := 0; index < rt.NumField(); index++ {
+			field := rt.Field(index)
+			if !field.IsExported() {
+				continue
+			}
+
+			if field.Type.Kind() == reflect.Pointer || hasPointerField(field.Type, visited) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+var (
+	gobEncoderType      = reflect.TypeFor[gob.GobEncoder]()
+	binaryMarshalerType = reflect.TypeFor[encoding.BinaryMarshaler]()
+)
+
+func encodeEntity(entity any) ([]byte, error) {
+	var buffer bytes.Buffer
+	err := gob.NewEncoder(&buffer).Encode(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func decodeEntity(value []byte, dest any) error {
+	return gob.NewDecoder(bytes.NewReader(value)).Decode(dest)
+}
+
+// getCachedEntities decode cached entities of ids into found keyed by fmt.Sprint(id), return ids not cached
+func getCachedEntities[T any](ctx context.Context, cache *entityCache, table string, ids []any, found map[string]T) []any {
+	keys := make([]string, len(ids))
+	for index, id := range ids {
+		keys[index] = cache.key(table, []any{id})
+	}
+
+	values := cache.get(ctx, keys...)
+	missing := make([]any, 0, len(ids))
+	for index, id := range ids {
+		var entity T
+		value, ok := values[keys[index]]
+		if ok && decodeEntity(value, &entity) == nil {
+			found[fmt.Sprint(id)] = entity
+			continue
+		}
+
+		missing = append(missing, id)
+	}
+
+	return missing
+}
+
+// cacheInvalidations keys to invalidate after the transaction is committed
+type cacheInvalidations struct {
+	mutex sync.Mutex
+	keys  []string
+}
+
+func (i *cacheInvalidations) add(keys ...string) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.keys = append(i.keys, keys...)
+}
+
+// canReadCache check whether entities of value can be read from cache, transactions and locking reads always read database
+func (s *DBContext) canReadCache(value any, options []LockOption) bool {
+	return s.dbo != nil && s.dbo.cache != nil && len(options) == 0 && !s.InTransaction() && cacheable(reflect.TypeOf(value))
+}
+
+// invalidateCache invalidate cached entities of table by primary keys, after commit in transactions
+func (s *DBContext) invalidateCache(ctx context.Context, table string, keys ...[]any) {
+	if s.dbo == nil || s.dbo.cache == nil || len(keys) == 0 {
+		return
+	}
+
+	cacheKeys := make([]string, len(keys))
+	for index, key := range keys {
+		cacheKeys[index] = s.dbo.cache.key(table, key)
+	}
+
+	if s.invalidations != nil {
+		s.invalidations.add(cacheKeys...)
+		return
+	}
+
+	s.dbo.cache.invalidate(ctx, cacheKeys...)
+}
+
+// InvalidateCache invalidate cached entities of T by primary keys, for changes bypassing Update, Save and Delete,
+// such as Exec or bulk updates. composite primary keys are passed as []any in the order of primary key columns
+func InvalidateCache[T any](ctx context.Context, keys ...any) error {
+	db, err := GetDB(ctx)
+	if err != nil {
+		return err
+	}
+
+	InvalidateCacheTx[T](ctx, db, keys...)
+	return nil
+}
+
+// InvalidateCacheTx invalidate cached entities of T by primary keys with db context, after commit in transactions
+func InvalidateCacheTx[T any](ctx context.Context, db *DBContext, keys ...any) {
+	values := make([][]any, len(keys))
+	for index, key := range keys {
+		composite, ok := key.([]any)
+		if !ok {
+			composite = []any{key}
+		}
+		values[index] = composite
+	}
+
+	db.invalidateCache(ctx, db.GetTableName(new(T)), values...)
+}
+
+// invalidateEntityCache invalidate cached entity by its primary keys
+func (s *DBContext) invalidateEntityCache(ctx context.Context, value any) {
+	if s.dbo == nil || s.dbo.cache == nil {
+		return
+	}
+
+	fields, err := s.getPrimaryFields(value)
+	if err != nil {
+		return
+	}
+
+	s.invalidateCache(ctx, s.GetTableName(value), primaryKeyValues(ctx, fields, value))
+}
+
+// primaryKeyValues get values of primary fields of value
+func primaryKeyValues(ctx context.Context, fields []*schema.Field, value any) []any {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+
+	keys := make([]any, len(fields))
+	for index, field := range fields {
+		keys[index], _ = field.ValueOf(ctx, rv)
+	}
+
+	return keys
+}
+
+// commitInvalidations invalidate cached entities changed in the committed transaction
+func (s *DBContext) commitInvalidations(ctx context.Context) {
+	if s.invalidations == nil || s.dbo == nil || s.dbo.cache == nil {
+		return
+	}
+
+	s.invalidations.mutex.Lock()
+	keys := s.invalidations.keys
+	s.invalidations.keys = nil
+	s.invalidations.mutex.Unlock()
+
+	s.dbo.cache.invalidate(ctx, keys...)
+}
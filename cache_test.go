@@ -0,0 +1,229 @@
+package dbo
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestMemoryCache(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache(2)
+
+	cache.Set(ctx, map[string][]byte{"a": []byte("1"), "b": []byte("2")}, time.Minute)
+	// a is used recently, b is evicted by c
+	cache.Get(ctx, "a")
+	cache.Set(ctx, map[string][]byte{"c": []byte("3")}, time.Minute)
+
+	values, _ := cache.Get(ctx, "a", "b", "c")
+	want := map[string][]byte{"a": []byte("1"), "c": []byte("3")}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("Get() = %q, want %q", values, want)
+	}
+
+	cache.Delete(ctx, "a")
+	cache.Set(ctx, map[string][]byte{"d": []byte("4")}, -time.Second)
+	values, _ = cache.Get(ctx, "a", "d")
+	if len(values) != 0 {
+		t.Errorf("deleted and expired keys should be missing, got %q", values)
+	}
+
+	if cache.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", cache.Len())
+	}
+}
+
+func TestEntityCacheLoad(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemoryCache(10)
+	cache := newEntityCache(&Config{Cache: memory, CacheTTL: time.Minute}, "root@tcp(127.0.0.1:3306)/tenant_7")
+	key := cache.key("table_a", []any{9})
+	if key != "dbo:127.0.0.1:3306/tenant_7:table_a:9" {
+		t.Errorf("key() = %s, want dbo:127.0.0.1:3306/tenant_7:table_a:9", key)
+	}
+
+	// dbo of other databases sharing the cache use other keys
+	other := newEntityCache(&Config{Cache: memory, CacheTTL: time.Minute}, "root@tcp(127.0.0.1:3306)/tenant_8")
+	if other.key("table_a", []any{9}) == key {
+		t.Errorf("keys of different databases should differ")
+	}
+
+	var queries atomic.Int32
+	release := make(chan struct{})
+	query := func(value **tableA) func() error {
+		return func() error {
+			queries.Add(1)
+			<-release
+			*value = &tableA{ID: 9, Name: "Unknown"}
+			return nil
+		}
+	}
+
+	// concurrent loads share a single query
+	var wg sync.WaitGroup
+	values := make([]*tableA, 5)
+	for index := range values {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := cache.load(ctx, key, &values[index], query(&values[index]))
+			if err != nil {
+				t.Errorf("load failed due to %v", err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	want := &tableA{ID: 9, Name: "Unknown"}
+	for _, value := range values {
+		if !reflect.DeepEqual(value, want) {
+			t.Errorf("load() = %v, want %v", value, want)
+		}
+	}
+
+	var cached *tableA
+	err := cache.load(ctx, key, &cached, query(&cached))
+	if err != nil || !reflect.DeepEqual(cached, want) {
+		t.Errorf("load() = %v, %v, want %v", cached, err, want)
+	}
+
+	if queries.Load() != 1 {
+		t.Errorf("queries = %d, want 1", queries.Load())
+	}
+
+	err = cache.load(ctx, cache.key("table_a", []any{10}), &cached, func() error { return gorm.ErrRecordNotFound })
+	if err != gorm.ErrRecordNotFound {
+		t.Errorf("load() error = %v, want %v", err, gorm.ErrRecordNotFound)
+	}
+
+	found := make(map[string]*tableA)
+	missing := getCachedEntities(ctx, cache, "table_a", []any{9, 10}, found)
+	if !reflect.DeepEqual(missing, []any{10}) || !reflect.DeepEqual(found["9"], want) {
+		t.Errorf("getCachedEntities() = %v, %v", found, missing)
+	}
+}
+
+func TestEntityCacheNullable(t *testing.T) {
+	type nullable struct {
+		ID      int64
+		Enabled *bool
+		Count   *int
+	}
+
+	type nested struct {
+		ID     int64
+		Detail struct{ Remark *string }
+	}
+
+	type plain struct {
+		ID      int64
+		Name    string
+		Created time.Time
+		Deleted gorm.DeletedAt
+	}
+
+	enabled, count := false, 0
+	value, err := encodeEntity(&nullable{ID: 1, Enabled: &enabled, Count: &count})
+	if err != nil {
+		t.Fatalf("encodeEntity() error = %v", err)
+	}
+
+	// gob loses pointers to zero values, so that such entities must not be cached
+	var decoded nullable
+	if decodeEntity(value, &decoded) != nil || decoded.Enabled != nil {
+		t.Errorf("gob should decode pointer to false as nil, got %v", decoded.Enabled)
+	}
+
+	for _, tt := range []struct {
+		value any
+		want  bool
+	}{
+		{&nullable{}, false},
+		{new(*nested), false},
+		{&plain{}, true},
+		{&tableA{}, true},
+	} {
+		if got := cacheable(reflect.TypeOf(tt.value)); got != tt.want {
+			t.Errorf("cacheable(%T) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestEntityCacheStaleLoad(t *testing.T) {
+	ctx := context.Background()
+	cache := newEntityCache(&Config{Cache: NewMemoryCache(10), CacheTTL: time.Minute}, "root@tcp(127.0.0.1:3306)/testdb")
+	key := cache.key("table_a", []any{9})
+
+	// the entity is changed and invalidated while it is loaded
+	var value *tableA
+	err := cache.load(ctx, key, &value, func() error {
+		value = &tableA{ID: 9, Name: "stale"}
+		cache.invalidate(ctx, key)
+		return nil
+	})
+	if err != nil || value.Name != "stale" {
+		t.Fatalf("load() = %v, %v", value, err)
+	}
+
+	values, _ := cache.cache.Get(ctx, key)
+	if len(values) != 0 {
+		t.Errorf("entity loaded across an invalidation should not be cached")
+	}
+
+	cache.set(ctx, cache.invalidations.Load()-1, map[string]any{key: value})
+	values, _ = cache.cache.Get(ctx, key)
+	if len(values) != 0 {
+		t.Errorf("entities loaded before an invalidation should not be cached")
+	}
+}
+
+func TestDeleteInvalidatesCache(t *testing.T) {
+	ctx := context.Background()
+	config := getDefaultConfig()
+	config.Cache = NewMemoryCache(10)
+	dbo := newTestDBO(t, config)
+
+	cached := &tableA{ID: 9, Name: "cached"}
+	cache := func(id int64) string {
+		key := dbo.cache.key("table_a", []any{id})
+		dbo.cache.set(ctx, dbo.cache.invalidations.Load(), map[string]any{key: &tableA{ID: id, Name: "cached"}})
+		return key
+	}
+
+	key := cache(9)
+	value, err := GetTx[*tableA](ctx, dryRun(ctx, dbo), 9)
+	if err != nil || !reflect.DeepEqual(value, cached) {
+		t.Fatalf("GetTx() = %v, %v, want cached entity", value, err)
+	}
+
+	_, err = DeleteTx[tableA](ctx, dryRun(ctx, dbo), 9)
+	if err != nil {
+		t.Fatalf("DeleteTx() error = %v", err)
+	}
+
+	values, _ := config.Cache.Get(ctx, key)
+	if len(values) != 0 {
+		t.Errorf("cached entity should be deleted, got %q", values)
+	}
+
+	// the deleted entity is read from database, which returns nothing in dry run
+	value, err = GetTx[*tableA](ctx, dryRun(ctx, dbo), 9)
+	if err == nil && reflect.DeepEqual(value, cached) {
+		t.Errorf("GetTx() after delete = %v, want cache missed", value)
+	}
+
+	// changes bypassing helpers are invalidated explicitly
+	key = cache(10)
+	InvalidateCacheTx[tableA](ctx, dryRun(ctx, dbo), int64(10))
+	values, _ = config.Cache.Get(ctx, key)
+	if len(values) != 0 {
+		t.Errorf("InvalidateCacheTx() should delete cached entity, got %q", values)
+	}
+}
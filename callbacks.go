@@ -0,0 +1,87 @@
+package dbo
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// statementStartKey instance key of statement start time
+const statementStartKey = "dbo:start"
+
+// registerCallbacks register statement callbacks of dbo
+func (s *DBO) registerCallbacks(db *gorm.DB) error {
+	return errors.Join(
+		registerStatementCallback(db, "dbo:start", true, func(db *gorm.DB) {
+			db.InstanceSet(statementStartKey, time.Now())
+		}),
+		registerStatementCallback(db, "dbo:sensitive", true, registerStatementSensitiveColumns),
+		registerTenantCallbacks(db, s.tenant),
+		registerStatementCallback(db, "dbo:trace", false, traceStatement(s.config)),
+		registerStatementCallback(db, "dbo:slow_query", false, s.slowQueries.check),
+		registerStatementCallback(db, "dbo:query_stats", false, s.queryStats.record),
+		registerNPlusOneCallbacks(db, s.nPlusOne),
+	)
+}
+
+// registerStatementCallback register fn before or after the gorm callback of every statement type
+func registerStatementCallback(db *gorm.DB, name string, before bool, fn func(*gorm.DB)) error {
+	callback := db.Callback()
+	if before {
+		return errors.Join(
+			callback.Create().Before("gorm:create").Register(name, fn),
+			callback.Query().Before("gorm:query").Register(name, fn),
+			callback.Update().Before("gorm:update").Register(name, fn),
+			callback.Delete().Before("gorm:delete").Register(name, fn),
+			callback.Row().Before("gorm:row").Register(name, fn),
+			callback.Raw().Before("gorm:raw").Register(name, fn),
+		)
+	}
+
+	return errors.Join(
+		callback.Create().After("gorm:create").Register(name, fn),
+		callback.Query().After("gorm:query").Register(name, fn),
+		callback.Update().After("gorm:update").Register(name, fn),
+		callback.Delete().After("gorm:delete").Register(name, fn),
+		callback.Row().After("gorm:row").Register(name, fn),
+		callback.Raw().After("gorm:raw").Register(name, fn),
+	)
+}
+
+// registerTenantCallbacks scope statements of tenant entities before gorm builds them
+func registerTenantCallbacks(db *gorm.DB, tenant *tenantScope) error {
+	callback := db.Callback()
+	return errors.Join(
+		callback.Create().Before("gorm:create").Register("dbo:tenant", tenant.create),
+		callback.Query().Before("gorm:query").Register("dbo:tenant", tenant.query),
+		callback.Update().Before("gorm:update").Register("dbo:tenant", tenant.update),
+		callback.Delete().Before("gorm:delete").Register("dbo:tenant", tenant.delete),
+		callback.Row().Before("gorm:row").Register("dbo:tenant", tenant.query),
+	)
+}
+
+// registerNPlusOneCallbacks warn N+1 queries after statements are executed, or reject them before in fail mode.
+// creates, updates and deletes are never select statements, they are not checked in fail mode
+func registerNPlusOneCallbacks(db *gorm.DB, detector *nPlusOneDetector) error {
+	if detector == nil || !detector.fail {
+		return registerStatementCallback(db, "dbo:n_plus_one", false, detector.check)
+	}
+
+	callback := db.Callback()
+	return errors.Join(
+		callback.Query().Before("gorm:query").Register("dbo:n_plus_one", detector.reject(callback.Query().Get("gorm:query"))),
+		callback.Row().Before("gorm:row").Register("dbo:n_plus_one", detector.reject(callback.Row().Get("gorm:row"))),
+		callback.Raw().Before("gorm:raw").Register("dbo:n_plus_one", detector.reject(nil)),
+	)
+}
+
+// statementDuration duration since the statement started, zero if start time is missing
+func statementDuration(db *gorm.DB) time.Duration {
+	start, ok := db.InstanceGet(statementStartKey)
+	if !ok {
+		return 0
+	}
+
+	return time.Since(start.(time.Time))
+}
@@ -1,12 +1,36 @@
 package dbo
 
 import (
+	"crypto/tls"
 	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Config dbo config
 type Config struct {
-	ConnectionString   string
+	// ConnectionString mysql dsn, takes precedence over MySQLConfig and the structured connection fields
+	ConnectionString string
+	// MySQLConfig driver config, takes precedence over the structured connection fields
+	MySQLConfig *mysql.Config
+	// structured connection fields, used when ConnectionString and MySQLConfig are empty.
+	// Charset and Params are appended to the dsn of MySQLConfig as well
+	User         string
+	Password     string
+	PasswordFile string
+	Host         string
+	Port         int
+	Database     string
+	Charset      string
+	Collation    string
+	Loc          *time.Location
+	TLSConfig    *tls.Config
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	Params       map[string]string
+
 	MaxOpenConns       int
 	MaxIdleConns       int
 	ConnMaxLifetime    time.Duration
@@ -15,15 +39,78 @@ type Config struct {
 	TransactionTimeout time.Duration
 	LogLevel           LogLevel
 	SlowThreshold      time.Duration
+	// SlowQueryHandler called after every sql slower than SlowThreshold
+	SlowQueryHandler SlowQueryHandler
+	// ExplainSlowQueries explain slow select on a separate connection and attach the plan to SlowQuery,
+	// slow queries are logged with the plan if SlowQueryHandler is nil
+	ExplainSlowQueries bool
+	// ExplainInterval at most one slow query is explained in every interval
+	ExplainInterval time.Duration
+	// QueryStatsLimit max count of normalized sql kept by query stats, query stats is disabled if zero
+	QueryStatsLimit int
+	// NPlusOneThreshold warn when select statements with the same fingerprint are executed more than threshold times
+	// within a context tracked by TrackQueries, detection is disabled if zero. for development only
+	NPlusOneThreshold int
+	// NPlusOneFail reject statements exceeding NPlusOneThreshold with ErrNPlusOneQuery before executing them
+	// instead of warning, such as in tests
+	NPlusOneFail bool
+	// QueryGuardMinRows QueryTx and CountTx without where clause are unsafe
+	// on tables with at least QueryGuardMinRows estimated rows, the check is disabled if zero
+	QueryGuardMinRows int64
+	// QueryGuardExplain statements of the guarded helpers are unsafe if explain shows full table scan
+	QueryGuardExplain bool
+	// QueryGuardReject reject unsafe statements with ErrUnsafeQuery, such as in development and tests,
+	// otherwise unsafe statements are logged only
+	QueryGuardReject bool
+	// Cache read-through cache of Get and GetMany outside transactions, invalidated by Update, Save and Delete.
+	// keys are prefixed by database address and name, so that a cache can be shared by dbo of tenants.
+	// caching is disabled if nil
+	Cache Cache
+	// CacheTTL expiration of cached entities
+	CacheTTL time.Duration
+	// TenantResolver resolve tenant of statements on entities with a field tagged with dbo:"tenant",
+	// tenant scoping is disabled if nil
+	TenantResolver TenantResolver
+	// SQLLogSampling log one of every SQLLogSampling successful sql at debug level, all are logged if zero or one.
+	// failed and slow sql are always logged
+	SQLLogSampling int
+
+	// ConnectRetryMaxWait max duration to retry connecting database on startup, no retry if zero
+	ConnectRetryMaxWait time.Duration
+	// ConnectRetryInterval interval before the first retry, doubled after every retry
+	ConnectRetryInterval time.Duration
+	// ConnectRetryJitter randomize retry interval by ±ratio, such as 0.2
+	ConnectRetryJitter float64
+	// ConnectAsync return from New immediately and connect in background, operations wait until connected
+	ConnectAsync bool
+
+	// Observers observe operations and transactions, such as metrics collectors
+	Observers []Observer
+
+	// TracerProvider provider of operation and transaction spans, tracing is disabled if nil
+	TracerProvider trace.TracerProvider
+	// TraceSQLParameters include parameter values in db.statement of spans, parameters are redacted by default
+	TraceSQLParameters bool
+
+	// SensitiveColumns columns masked in logs and traced sql, as "column" or "table.column".
+	// fields tagged with dbo:"sensitive" are masked as well
+	SensitiveColumns []string
 }
 
 func getDefaultConfig() *Config {
 	return &Config{
 		DBType:             MySQL,
+		Charset:            "utf8mb4",
 		TransactionTimeout: time.Second * 3,
 		// default log level, include INFO & WARN & ERROR logs
 		LogLevel:      Info,
 		SlowThreshold: 200 * time.Millisecond,
+		// explain at most one slow query per minute if enabled
+		ExplainInterval: time.Minute,
+		CacheTTL:        10 * time.Minute,
+		// retry is disabled until ConnectRetryMaxWait is set
+		ConnectRetryInterval: time.Second,
+		ConnectRetryJitter:   0.2,
 	}
 }
 
@@ -36,6 +123,99 @@ func WithConnectionString(connectionString string) Option {
 	}
 }
 
+// WithUser set database user, used when ConnectionString is empty
+func WithUser(user string) Option {
+	return func(c *Config) {
+		c.User = user
+	}
+}
+
+// WithPassword set database password, used when ConnectionString is empty
+func WithPassword(password string) Option {
+	return func(c *Config) {
+		c.Password = password
+	}
+}
+
+// WithPasswordFile set the file contains database password, used when ConnectionString is empty
+func WithPasswordFile(passwordFile string) Option {
+	return func(c *Config) {
+		c.PasswordFile = passwordFile
+	}
+}
+
+// WithHost set database address such as 127.0.0.1:3306, used when ConnectionString is empty
+func WithHost(host string) Option {
+	return func(c *Config) {
+		c.Host = host
+	}
+}
+
+// WithPort set database port, used when ConnectionString is empty
+func WithPort(port int) Option {
+	return func(c *Config) {
+		c.Port = port
+	}
+}
+
+// WithDatabase set database name, used when ConnectionString is empty
+func WithDatabase(database string) Option {
+	return func(c *Config) {
+		c.Database = database
+	}
+}
+
+// WithCharset set connection charset, default is utf8mb4
+func WithCharset(charset string) Option {
+	return func(c *Config) {
+		c.Charset = charset
+	}
+}
+
+// WithCollation set connection collation
+func WithCollation(collation string) Option {
+	return func(c *Config) {
+		c.Collation = collation
+	}
+}
+
+// WithLoc set location for time.Time values
+func WithLoc(loc *time.Location) Option {
+	return func(c *Config) {
+		c.Loc = loc
+	}
+}
+
+// WithTLSConfig set tls config of connections
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(c *Config) {
+		c.TLSConfig = tlsConfig
+	}
+}
+
+// WithTimeouts set dial, read and write timeout of connections
+func WithTimeouts(dial, read, write time.Duration) Option {
+	return func(c *Config) {
+		c.DialTimeout = dial
+		c.ReadTimeout = read
+		c.WriteTimeout = write
+	}
+}
+
+// WithMySQLConfig set mysql driver config, Charset and Params are still appended to the generated dsn
+func WithMySQLConfig(mysqlConfig *mysql.Config) Option {
+	return func(c *Config) {
+		c.MySQLConfig = mysqlConfig
+	}
+}
+
+// WithParams set dsn parameters such as parseTime=true, used when ConnectionString is empty
+func WithParams(params map[string]string) Option {
+	return func(c *Config) {
+		c.Params = params
+	}
+}
+
 func WithDBType(dbType DBType) Option {
 	return func(c *Config) {
 		c.DBType = dbType
@@ -54,6 +234,18 @@ func WithMaxIdleConns(maxIdleConns int) Option {
 	}
 }
 
+func WithConnMaxLifetime(connMaxLifetime time.Duration) Option {
+	return func(c *Config) {
+		c.ConnMaxLifetime = connMaxLifetime
+	}
+}
+
+func WithConnMaxIdleTime(connMaxIdleTime time.Duration) Option {
+	return func(c *Config) {
+		c.ConnMaxIdleTime = connMaxIdleTime
+	}
+}
+
 func WithTransactionTimeout(timeout time.Duration) Option {
 	return func(c *Config) {
 		c.TransactionTimeout = timeout
@@ -65,3 +257,127 @@ func WithLogLevel(logLevel LogLevel) Option {
 		c.LogLevel = logLevel
 	}
 }
+
+func WithSlowThreshold(slowThreshold time.Duration) Option {
+	return func(c *Config) {
+		c.SlowThreshold = slowThreshold
+	}
+}
+
+// WithSlowQueryHandler call handler after every sql slower than SlowThreshold
+func WithSlowQueryHandler(handler SlowQueryHandler) Option {
+	return func(c *Config) {
+		c.SlowQueryHandler = handler
+	}
+}
+
+// WithExplainSlowQueries explain at most one slow select in every interval
+func WithExplainSlowQueries(interval time.Duration) Option {
+	return func(c *Config) {
+		c.ExplainSlowQueries = true
+		c.ExplainInterval = interval
+	}
+}
+
+// WithQueryStats keep statistics of at most limit normalized sql, see DBO.QueryStats
+func WithQueryStats(limit int) Option {
+	return func(c *Config) {
+		c.QueryStatsLimit = limit
+	}
+}
+
+// WithNPlusOneDetection detect select statements with the same fingerprint executed more than threshold times
+// within a context tracked by TrackQueries or a transaction, fail the statements instead of warning if fail is true
+func WithNPlusOneDetection(threshold int, fail bool) Option {
+	return func(c *Config) {
+		c.NPlusOneThreshold = threshold
+		c.NPlusOneFail = fail
+	}
+}
+
+// WithQueryGuard check statements of QueryTx and CountTx for missing where clause
+// on tables with at least minRows rows, and for full table scan if explain is true. unsafe statements are
+// rejected if reject is true, otherwise logged
+func WithQueryGuard(minRows int64, explain bool, reject bool) Option {
+	return func(c *Config) {
+		c.QueryGuardMinRows = minRows
+		c.QueryGuardExplain = explain
+		c.QueryGuardReject = reject
+	}
+}
+
+// WithCache read Get and GetMany through cache, entities expire after ttl.
+// cached entities are invalidated by Update, Save and Delete of dbo, after commit in transactions, changes by Exec
+// or bulk updates are invalidated by InvalidateCache. entities loaded across an invalidation of this dbo are
+// not cached, but changes by other processes are only seen after ttl. entities with pointer fields are never cached, gob decodes pointers to zero values as nil
+func WithCache(cache Cache, ttl time.Duration) Option {
+	return func(c *Config) {
+		c.Cache = cache
+		c.CacheTTL = ttl
+	}
+}
+
+// WithTenantResolver scope statements on entities with a field tagged with dbo:"tenant" by the tenant resolved
+// from context. queries, updates and deletes are filtered by the tenant and inserts are assigned to it,
+// statements without tenant fail with ErrTenantRequired unless the context is bypassed by WithoutTenant.
+// Raw and Exec are not scoped
+func WithTenantResolver(resolver TenantResolver) Option {
+	return func(c *Config) {
+		c.TenantResolver = resolver
+	}
+}
+
+// WithSQLLogSampling log one of every n successful sql at debug level
+func WithSQLLogSampling(n int) Option {
+	return func(c *Config) {
+		c.SQLLogSampling = n
+	}
+}
+
+// WithConnectRetry retry connecting database on startup until maxWait exceeded.
+// interval is doubled after every retry and randomized by ±jitter ratio
+func WithConnectRetry(maxWait, interval time.Duration, jitter float64) Option {
+	return func(c *Config) {
+		c.ConnectRetryMaxWait = maxWait
+		c.ConnectRetryInterval = interval
+		c.ConnectRetryJitter = jitter
+	}
+}
+
+// WithConnectAsync return from New immediately and connect in background,
+// the first operation waits until database is connected
+func WithConnectAsync() Option {
+	return func(c *Config) {
+		c.ConnectAsync = true
+	}
+}
+
+// WithConfig replace all config fields, such as config loaded by LoadConfigFromEnv or LoadConfigFromFile
+func WithConfig(config *Config) Option {
+	return func(c *Config) {
+		*c = *config
+	}
+}
+
+// WithTracing enable OpenTelemetry spans of operations and transactions,
+// parameter values are included in db.statement if includeParameters is true
+func WithTracing(provider trace.TracerProvider, includeParameters bool) Option {
+	return func(c *Config) {
+		c.TracerProvider = provider
+		c.TraceSQLParameters = includeParameters
+	}
+}
+
+// WithSensitiveColumns mask values of columns in logs and traced sql, columns are "column" or "table.column"
+func WithSensitiveColumns(columns ...string) Option {
+	return func(c *Config) {
+		c.SensitiveColumns = append(c.SensitiveColumns, columns...)
+	}
+}
+
+// WithObserver add observer of operations and transactions
+func WithObserver(observer Observer) Option {
+	return func(c *Config) {
+		c.Observers = append(c.Observers, observer)
+	}
+}
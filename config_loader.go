@@ -0,0 +1,306 @@
+package dbo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/nzai/log"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	locationType = reflect.TypeOf(&time.Location{})
+)
+
+// LoadConfigFromEnv load config from environment variables named by prefix and the upper snake case field name,
+// such as DBO_CONNECTION_STRING, DBO_TRANSACTION_TIMEOUT=3s and DBO_PARAMS=sql_mode=ANSI,time_zone=UTC.
+// fields without environment variable keep the default values
+func LoadConfigFromEnv(prefix string) (*Config, error) {
+	config := getDefaultConfig()
+	err := walkConfigFields(config, func(name string, field reflect.Value) error {
+		key := prefix + strings.ToUpper(name)
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			return nil
+		}
+
+		err := setConfigField(field, value)
+		if err != nil {
+			return fmt.Errorf("invalid environment variable %s: %w", key, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// LoadConfigFromFile load config from yaml or json file. keys are field names in camel or snake case,
+// such as connectionString or connection_string, durations are strings such as "3s"
+func LoadConfigFromFile(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &values)
+	case ".json":
+		err = json.Unmarshal(content, &values)
+	default:
+		return nil, fmt.Errorf("unsupported config file %s, yaml or json expected", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse config file %s failed: %w", path, err)
+	}
+
+	fields := make(map[string]reflect.Value)
+	config := getDefaultConfig()
+	_ = walkConfigFields(config, func(name string, field reflect.Value) error {
+		fields[normalizeConfigKey(name)] = field
+		return nil
+	})
+
+	for key, value := range values {
+		field, ok := fields[normalizeConfigKey(key)]
+		if !ok {
+			return nil, fmt.Errorf("unknown config %s in %s", key, path)
+		}
+
+		err = setConfigFieldValue(field, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config %s in %s: %w", key, path, err)
+		}
+	}
+
+	return config, nil
+}
+
+// Validate check config before connecting to database
+func (c *Config) Validate() error {
+	errs := make([]error, 0)
+	if c.DBType.DriverName() == "" {
+		errs = append(errs, fmt.Errorf("unsupported database type %q, expected %q", c.DBType, MySQL))
+	}
+
+	switch c.LogLevel {
+	case Silent, Error, Warn, Info:
+	default:
+		errs = append(errs, fmt.Errorf("invalid log level %q, expected one of %q, %q, %q, %q", c.LogLevel, Silent, Error, Warn, Info))
+	}
+
+	if c.ConnectionString != "" {
+		_, err := mysql.ParseDSN(c.ConnectionString)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid connection string %s: %w", RedactDSN(c.ConnectionString), err))
+		}
+	}
+
+	if c.Password != "" && c.PasswordFile != "" {
+		errs = append(errs, errors.New("password and password file can not be set at the same time"))
+	}
+
+	if c.Port < 0 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("invalid port %d", c.Port))
+	}
+
+	if c.TransactionTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("transaction timeout must be positive, got %s", c.TransactionTimeout))
+	}
+
+	if c.ConnectRetryMaxWait > 0 && c.ConnectRetryInterval <= 0 {
+		errs = append(errs, fmt.Errorf("connect retry interval must be positive, got %s", c.ConnectRetryInterval))
+	}
+
+	if c.ConnectRetryJitter < 0 || c.ConnectRetryJitter >= 1 {
+		errs = append(errs, fmt.Errorf("connect retry jitter must be in [0, 1), got %v", c.ConnectRetryJitter))
+	}
+
+	if c.ExplainSlowQueries && c.SlowThreshold <= 0 {
+		errs = append(errs, errors.New("explain slow queries requires a positive slow threshold"))
+	}
+
+	if c.Cache != nil && c.CacheTTL <= 0 {
+		errs = append(errs, fmt.Errorf("cache ttl must be positive, got %s", c.CacheTTL))
+	}
+
+	// accepted before validation was introduced, warn only
+	if c.MaxOpenConns > 0 && c.MaxIdleConns > c.MaxOpenConns {
+		log.Warn(context.Background(), "max idle conns is greater than max open conns, it is reduced to max open conns",
+			log.Int("maxIdleConns", c.MaxIdleConns),
+			log.Int("maxOpenConns", c.MaxOpenConns))
+	}
+
+	return errors.Join(errs...)
+}
+
+// walkConfigFields call fn with snake case name of every config field which can be loaded from text
+func walkConfigFields(config *Config, fn func(name string, field reflect.Value) error) error {
+	namer := schema.NamingStrategy{}
+	rv := reflect.ValueOf(config).Elem()
+	rt := rv.Type()
+	for index := 0; index < rt.NumField(); index++ {
+		field := rv.Field(index)
+		if !rt.Field(index).IsExported() || !isLoadableType(field.Type()) {
+			continue
+		}
+
+		err := fn(namer.ColumnName("", rt.Field(index).Name), field)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func isLoadableType(t reflect.Type) bool {
+	if t == locationType {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.String
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.String
+	default:
+		return false
+	}
+}
+
+// setConfigFieldValue set field by decoded yaml or json value
+func setConfigFieldValue(field reflect.Value, value any) error {
+	switch v := value.(type) {
+	case nil:
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	case map[string]any:
+		if field.Kind() != reflect.Map {
+			return fmt.Errorf("unexpected object for %s", field.Type())
+		}
+
+		m := reflect.MakeMapWithSize(field.Type(), len(v))
+		for key, item := range v {
+			m.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(fmt.Sprint(item)))
+		}
+		field.Set(m)
+		return nil
+	case []any:
+		if field.Kind() != reflect.Slice {
+			return fmt.Errorf("unexpected list for %s", field.Type())
+		}
+
+		s := reflect.MakeSlice(field.Type(), 0, len(v))
+		for _, item := range v {
+			s = reflect.Append(s, reflect.ValueOf(fmt.Sprint(item)))
+		}
+		field.Set(s)
+		return nil
+	case float64:
+		// json numbers are decoded as float64
+		if field.CanInt() || field.CanUint() {
+			return setConfigField(field, strconv.FormatFloat(v, 'f', -1, 64))
+		}
+		return setConfigField(field, fmt.Sprint(v))
+	default:
+		return setConfigField(field, fmt.Sprint(v))
+	}
+}
+
+// setConfigField set field by text, such as environment variables
+func setConfigField(field reflect.Value, value string) error {
+	value = strings.TrimSpace(value)
+	switch {
+	case field.Type() == durationType:
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, expected format such as 300ms or 3s", value)
+		}
+		field.SetInt(int64(duration))
+	case field.Type() == locationType:
+		loc, err := time.LoadLocation(value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(loc))
+	case field.Kind() == reflect.String:
+		field.SetString(value)
+	case field.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid bool %q", value)
+		}
+		field.SetBool(b)
+	case field.CanInt():
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		field.SetInt(i)
+	case field.CanUint():
+		u, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", value)
+		}
+		field.SetUint(u)
+	case field.CanFloat():
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		field.SetFloat(f)
+	case field.Kind() == reflect.Map:
+		// k1=v1,k2=v2
+		m := reflect.MakeMap(field.Type())
+		for _, pair := range strings.Split(value, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+
+			key, item, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("invalid pair %q, expected key=value", pair)
+			}
+			m.SetMapIndex(reflect.ValueOf(strings.TrimSpace(key)), reflect.ValueOf(strings.TrimSpace(item)))
+		}
+		field.Set(m)
+	case field.Kind() == reflect.Slice:
+		s := reflect.MakeSlice(field.Type(), 0, 0)
+		for _, item := range strings.Split(value, ",") {
+			if strings.TrimSpace(item) != "" {
+				s = reflect.Append(s, reflect.ValueOf(strings.TrimSpace(item)))
+			}
+		}
+		field.Set(s)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+
+	return nil
+}
+
+// normalizeConfigKey normalize camel case and snake case keys, connectionString and connection_string are the same
+func normalizeConfigKey(key string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+}
@@ -0,0 +1,93 @@
+package dbo
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	t.Setenv("DBO_TEST_CONNECTION_STRING", "root:123456@tcp(127.0.0.1:3306)/testdb")
+	t.Setenv("DBO_TEST_MAX_OPEN_CONNS", "20")
+	t.Setenv("DBO_TEST_TRANSACTION_TIMEOUT", "10s")
+	t.Setenv("DBO_TEST_LOG_LEVEL", "Warn")
+	t.Setenv("DBO_TEST_PARAMS", "sql_mode=ANSI, time_zone=UTC")
+
+	config, err := LoadConfigFromEnv("DBO_TEST_")
+	if err != nil {
+		t.Fatalf("LoadConfigFromEnv() failed due to %v", err)
+	}
+
+	if config.ConnectionString != "root:123456@tcp(127.0.0.1:3306)/testdb" ||
+		config.MaxOpenConns != 20 ||
+		config.TransactionTimeout != 10*time.Second ||
+		config.LogLevel != Warn ||
+		!reflect.DeepEqual(config.Params, map[string]string{"sql_mode": "ANSI", "time_zone": "UTC"}) {
+		t.Errorf("LoadConfigFromEnv() = %+v", config)
+	}
+
+	// default values
+	if config.DBType != MySQL || config.SlowThreshold != 200*time.Millisecond {
+		t.Errorf("LoadConfigFromEnv() = %+v", config)
+	}
+
+	t.Setenv("DBO_TEST_SLOW_THRESHOLD", "200")
+	_, err = LoadConfigFromEnv("DBO_TEST_")
+	if err == nil {
+		t.Errorf("LoadConfigFromEnv() should fail for duration without unit")
+	}
+}
+
+func TestLoadConfigFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dbo.yaml")
+	err := os.WriteFile(path, []byte(`
+host: 127.0.0.1
+port: 3306
+user: root
+database: testdb
+maxIdleConns: 5
+conn_max_lifetime: 1h
+logLevel: Silent
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfigFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFromFile() failed due to %v", err)
+	}
+
+	if config.Host != "127.0.0.1" || config.Port != 3306 || config.MaxIdleConns != 5 ||
+		config.ConnMaxLifetime != time.Hour || config.LogLevel != Silent {
+		t.Errorf("LoadConfigFromFile() = %+v", config)
+	}
+
+	err = config.Validate()
+	if err != nil {
+		t.Errorf("Validate() failed due to %v", err)
+	}
+
+	// values accepted before validation was introduced remain valid
+	config.MaxIdleConns, config.MaxOpenConns = 20, 10
+	config.SlowThreshold = -1
+	err = config.Validate()
+	if err != nil {
+		t.Errorf("Validate() failed due to %v", err)
+	}
+
+	config.MaxIdleConns = -1
+	err = config.Validate()
+	if err != nil {
+		t.Errorf("Validate() failed due to %v", err)
+	}
+
+	config.LogLevel = "Debug"
+	config.DBType = "postgres"
+	err = config.Validate()
+	if err == nil {
+		t.Errorf("Validate() should fail for invalid log level and db type")
+	}
+}
@@ -0,0 +1,162 @@
+package dbo
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/nzai/log"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxConnectRetryInterval max interval between connect retries
+	maxConnectRetryInterval = 30 * time.Second
+	// pingTimeout timeout of every ping, so that an unreachable database does not block connecting forever
+	pingTimeout = 5 * time.Second
+)
+
+// openDB open database and apply pool settings, connection is not established if skipVersion is true
+// because both version query and automatic ping are skipped
+func (s *DBO) openDB(ctx context.Context, dsn string, skipVersion bool) (*gorm.DB, error) {
+	config := s.config
+	var db *gorm.DB
+	var err error
+	switch config.DBType {
+	case MySQL:
+		db, err = gorm.Open(mysql.New(mysql.Config{
+			DriverName:                config.DBType.DriverName(),
+			DSN:                       dsn,
+			SkipInitializeWithVersion: skipVersion,
+		}), &gorm.Config{
+			QueryFields:          true,
+			DisableAutomaticPing: skipVersion,
+			Logger:               newSQLLogger(config),
+		})
+	default:
+		log.Panic(ctx, "unsupported database type", log.String("databaseType", config.DBType.String()))
+	}
+
+	if err != nil {
+		log.Warn(ctx, "init database connection failed",
+			log.Err(err),
+			log.String("databaseType", config.DBType.String()),
+			log.String("connectionString", RedactDSN(dsn)))
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Warn(ctx, "get DB failed",
+			log.Err(err),
+			log.String("databaseType", config.DBType.String()),
+			log.String("connectionString", RedactDSN(dsn)))
+		return nil, err
+	}
+
+	if config.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(config.MaxOpenConns)
+	}
+
+	if config.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(config.MaxIdleConns)
+	}
+
+	if config.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(config.ConnMaxLifetime)
+	}
+
+	if config.ConnMaxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+	}
+
+	err = s.registerCallbacks(db)
+	if err != nil {
+		log.Warn(ctx, "register callbacks failed", log.Err(err))
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// pingDB check database connection
+func pingDB(ctx context.Context, config *Config, db *gorm.DB, dsn string) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Warn(ctx, "get DB failed",
+			log.Err(err),
+			log.String("databaseType", config.DBType.String()),
+			log.String("connectionString", RedactDSN(dsn)))
+		return err
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	err = sqlDB.PingContext(pingCtx)
+	if err != nil {
+		log.Warn(ctx, "ping datebase failed",
+			log.Err(err),
+			log.String("databaseType", config.DBType.String()),
+			log.String("connectionString", RedactDSN(dsn)))
+		return err
+	}
+
+	return nil
+}
+
+// retryConnect call connect until it succeeds, ConnectRetryMaxWait exceeded or ctx is done.
+// the interval starts from ConnectRetryInterval and doubles after every attempt
+func retryConnect(ctx context.Context, config *Config, connect func() error) error {
+	start := time.Now()
+	interval := config.ConnectRetryInterval
+	for attempt := 1; ; attempt++ {
+		err := connect()
+		if err == nil {
+			if attempt > 1 {
+				log.Info(ctx, "connect database successfully after retries",
+					log.Int("attempts", attempt),
+					log.Duration("duration", time.Since(start)))
+			}
+			return nil
+		}
+
+		elapsed := time.Since(start)
+		if elapsed >= config.ConnectRetryMaxWait {
+			if attempt > 1 {
+				log.Warn(ctx, "connect database failed after retries",
+					log.Err(err),
+					log.Int("attempts", attempt),
+					log.Duration("duration", elapsed))
+			}
+			return err
+		}
+
+		wait := min(jitter(interval, config.ConnectRetryJitter), config.ConnectRetryMaxWait-elapsed)
+		log.Info(ctx, "connect database failed, retrying",
+			log.Err(err),
+			log.Int("attempt", attempt),
+			log.Duration("wait", wait),
+			log.Duration("elapsed", elapsed),
+			log.Duration("maxWait", config.ConnectRetryMaxWait))
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			log.Info(ctx, "connect database stopped", log.Err(ctx.Err()), log.Int("attempts", attempt))
+			return ctx.Err()
+		}
+		interval = min(interval*2, maxConnectRetryInterval)
+	}
+}
+
+// jitter randomize interval by ±ratio
+func jitter(interval time.Duration, ratio float64) time.Duration {
+	if ratio <= 0 || interval <= 0 {
+		return interval
+	}
+
+	delta := float64(interval) * ratio * (rand.Float64()*2 - 1)
+	return max(interval+time.Duration(delta), 0)
+}
@@ -0,0 +1,81 @@
+package dbo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitReadyReconnect(t *testing.T) {
+	ctx := context.Background()
+	config := getDefaultConfig()
+	config.ConnectionString = "root:123456@tcp(127.0.0.1:1)/testdb"
+	config.ConnectRetryInterval = time.Hour
+
+	dbo := newTestDBO(t, config)
+	failed := errors.New("connect failed")
+	dbo.readyErr, dbo.lastConnect = failed, time.Now()
+	close(dbo.ready)
+
+	// no reconnect within ConnectRetryInterval
+	err := dbo.WaitReady(ctx)
+	if err != failed {
+		t.Errorf("WaitReady() error = %v, want %v", err, failed)
+	}
+
+	// reconnect after ConnectRetryInterval, the database is still unreachable
+	dbo.lastConnect = time.Now().Add(-2 * time.Hour)
+	err = dbo.WaitReady(ctx)
+	if err == nil || err == failed {
+		t.Errorf("WaitReady() error = %v, want error of the new attempt", err)
+	}
+}
+
+func TestCloseStopsConnecting(t *testing.T) {
+	dbo, err := NewWithConfig(func(c *Config) {
+		c.ConnectionString = "root:123456@tcp(127.0.0.1:1)/testdb?timeout=1s"
+		c.ConnectAsync = true
+		c.ConnectRetryInterval = 10 * time.Millisecond
+		c.ConnectRetryMaxWait = time.Hour
+	})
+	if err != nil {
+		t.Fatalf("NewWithConfig() error = %v", err)
+	}
+
+	dbo.Close(context.Background())
+
+	select {
+	case <-dbo.ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("connecting in background is not stopped by Close")
+	}
+
+	if err := dbo.readyError(); !errors.Is(err, context.Canceled) {
+		t.Errorf("readyError() = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestReconnectUnlocked(t *testing.T) {
+	delay := 200 * time.Millisecond
+	dbo := newPingDBO(t, delay)
+	failed := errors.New("connect failed")
+	dbo.readyErr, dbo.lastConnect = failed, time.Now().Add(-time.Hour)
+
+	done := make(chan error)
+	go func() {
+		done <- dbo.WaitReady(context.Background())
+	}()
+
+	// the ping of reconnect is in progress, the last error is returned without waiting for it
+	time.Sleep(delay / 4)
+	start := time.Now()
+	err := dbo.readyError()
+	if err != failed || time.Since(start) > delay/2 {
+		t.Errorf("readyError() = %v after %v, want %v without waiting for ping", err, time.Since(start), failed)
+	}
+
+	if err = <-done; err != nil {
+		t.Errorf("WaitReady() error = %v, want nil", err)
+	}
+}
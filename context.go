@@ -1,41 +1,22 @@
 package dbo
 
 import (
-	"github.com/nzai/log"
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 // DBContext db with context
 type DBContext struct {
 	*gorm.DB
-}
-
-// Print print sql log
-func (s *DBContext) Printf(format string, v ...interface{}) {
-	switch len(v) {
-	case 4:
-		// v[4]: [fileWithLineNum(), duration, rowAffected, sql]
-		// example: ["/home/test/code.go:27",16.845856,1,"INSERT INTO `test_table` (`id`,`name`) VALUES ('1234','bad')"]
-		log.Debug(s.Statement.Context, v[3].(string),
-			log.String("logType", "sql"),
-			log.String("lineNum", v[0].(string)),
-			log.Any("rowsAffected", v[2]),
-			log.Float64("duration", v[1].(float64)))
-	case 5:
-		// v[5]: [fileWithLineNum(), "SLOW SQL >= 1µs", duration, rowAffected, sql]
-		// example: ["/home/test/code.go:27","SLOW SQL >= 1µs",16.845856,1,"INSERT INTO `test_table` (`id`,`name`) VALUES ('1234','bad')"]
-		log.Debug(s.Statement.Context, v[4].(string),
-			log.String("logType", "sql"),
-			log.String("lineNum", v[0].(string)),
-			log.Any("rowsAffected", v[3]),
-			log.Float64("duration", v[2].(float64)),
-			log.Any("extra", v[1]))
-	default:
-		log.Debug(s.DB.Statement.Context, "invalid sql log",
-			log.String("logType", "sql"),
-			log.String("format", format),
-			log.Any("args", v))
-	}
+	dbo *DBO
+	// invalidations cached entities to invalidate after commit, set in transactions of GetTrans
+	invalidations *cacheInvalidations
 }
 
 // GetTableName get database table name of value
@@ -49,6 +30,139 @@ func (s *DBContext) GetTableName(value interface{}) string {
 	return stmt.Schema.Table
 }
 
+// maskValue mask sensitive fields of value for logging
+func (s *DBContext) maskValue(value interface{}) interface{} {
+	sch, err := s.GetSchema(value)
+	if err != nil {
+		return value
+	}
+
+	var columns sensitiveColumns
+	if s.dbo != nil {
+		columns = s.dbo.sensitive
+	}
+
+	return columns.maskValue(sch, value)
+}
+
+// filterParams mask sql parameters bound to sensitive columns for logging
+func (s *DBContext) filterParams(sql string, params []interface{}) []interface{} {
+	var columns sensitiveColumns
+	if s.dbo != nil {
+		columns = s.dbo.sensitive
+	}
+
+	return columns.filterParams(sql, params)
+}
+
+// maskCondition get where clauses and parameters of condition on table of model for logging,
+// parameters bound to sensitive columns are masked
+func (s *DBContext) maskCondition(model any, condition QueryCondition) map[string]any {
+	if condition == nil {
+		return nil
+	}
+
+	// tagged columns of model are registered on executing statements, which may not be executed yet
+	var table string
+	sch, err := s.GetSchema(model)
+	if err == nil {
+		registerSensitiveColumns(sch)
+		table = sch.Table
+	}
+
+	wheres, parameters := condition.GetConditions()
+	sql := fmt.Sprintf("SELECT * FROM `%s` WHERE %s", table, strings.Join(wheres, " and "))
+	return map[string]any{
+		"wheres":     wheres,
+		"parameters": s.filterParams(sql, parameters),
+	}
+}
+
+// InTransaction check whether db is in a transaction
+func (s *DBContext) InTransaction() bool {
+	_, ok := s.Statement.ConnPool.(gorm.TxCommitter)
+	return ok
+}
+
+// GetSchema get gorm schema of value
+func (s *DBContext) GetSchema(value interface{}) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: s.DB}
+	err := stmt.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return stmt.Schema, nil
+}
+
+// getPrimaryFields get primary fields of value, fallback to the "id" column
+func (s *DBContext) getPrimaryFields(value interface{}) ([]*schema.Field, error) {
+	sch, err := s.GetSchema(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sch.PrimaryFields) > 0 {
+		return sch.PrimaryFields, nil
+	}
+
+	field := sch.LookUpField("id")
+	if field == nil {
+		return nil, fmt.Errorf("%w: table %s has no primary key", ErrInvalidPrimaryKey, sch.Table)
+	}
+
+	return []*schema.Field{field}, nil
+}
+
+// getPrimaryField get the single primary field of value
+func (s *DBContext) getPrimaryField(value interface{}) (*schema.Field, error) {
+	fields, err := s.getPrimaryFields(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fields) != 1 {
+		return nil, fmt.Errorf("%w: table %s has composite primary key %v", ErrInvalidPrimaryKey, s.GetTableName(value), fieldNames(fields))
+	}
+
+	return fields[0], nil
+}
+
+// fieldNames get db names of fields
+func fieldNames(fields []*schema.Field) []string {
+	names := make([]string, len(fields))
+	for index, field := range fields {
+		names[index] = field.DBName
+	}
+
+	return names
+}
+
+// primaryKeyValue convert id to the type of primary key field, id is returned as is if it can not be converted
+func primaryKeyValue(ctx context.Context, field *schema.Field, id any) any {
+	// numeric strings are parsed in decimal as mysql does, gorm parses them with prefixes such as 0 for octal
+	if text, ok := id.(string); ok {
+		switch field.IndirectFieldType.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if value, err := strconv.ParseInt(strings.TrimSpace(text), 10, 64); err == nil {
+				id = value
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if value, err := strconv.ParseUint(strings.TrimSpace(text), 10, 64); err == nil {
+				id = value
+			}
+		}
+	}
+
+	rv := reflect.New(field.Schema.ModelType).Elem()
+	if field.Set(ctx, rv, id) != nil {
+		return id
+	}
+
+	value, _ := field.ValueOf(ctx, rv)
+	return value
+}
+
 // ResetCondition reset session query conditions
 func (s *DBContext) ResetCondition() *DBContext {
 	s.DB = s.DB.Session(&gorm.Session{NewDB: true})
@@ -4,22 +4,52 @@ import (
 	// mysql driver
 	"context"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/nzai/log"
-	"gorm.io/driver/mysql"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 var (
 	globalDBO   *DBO
 	globalMutex sync.Mutex
+	// lazyOptions options to create global dbo on first use, lazy construction is disabled if nil
+	lazyOptions []Option
 )
 
 // DBO database operator
 type DBO struct {
 	db     *gorm.DB
 	config *Config
+
+	// ready is closed when database is connected or connecting failed, in which case readyErr is set
+	// until a later reconnect succeeds
+	ready       chan struct{}
+	readyMutex  sync.Mutex
+	readyErr    error
+	lastConnect time.Time
+	dsn         string
+	// cancelConnect stop connecting in background, called on closing
+	cancelConnect context.CancelFunc
+
+	mutex        sync.Mutex
+	closed       bool
+	transactions sync.WaitGroup
+
+	// lastWaitCount pool wait count of the last health check
+	lastWaitCount atomic.Int64
+
+	observers   atomic.Pointer[[]Observer]
+	tracer      trace.Tracer
+	sensitive   sensitiveColumns
+	slowQueries *slowQueryMonitor
+	queryStats  *queryStats
+	nPlusOne    *nPlusOneDetector
+	guard       *queryGuard
+	cache       *entityCache
+	tenant      *tenantScope
 }
 
 // MustGetDB get db context otherwise panic
@@ -32,9 +62,18 @@ func MustGetDB(ctx context.Context) *DBContext {
 	return dbContext
 }
 
-// GetDB get db context
+// GetDB get db context, of the tenant of ctx if the global tenant router is set
 func GetDB(ctx context.Context) (*DBContext, error) {
-	dbo, err := GetGlobal()
+	dbo, err := getDBO(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if dbo.isClosed() {
+		return nil, ErrClosed
+	}
+
+	err = dbo.WaitReady(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -42,6 +81,33 @@ func GetDB(ctx context.Context) (*DBContext, error) {
 	return dbo.GetDB(ctx), nil
 }
 
+// Init create global dbo
+func Init(options ...Option) error {
+	dbo, err := New(options...)
+	if err != nil {
+		return err
+	}
+
+	ReplaceGlobal(dbo)
+	return nil
+}
+
+// MustInit create global dbo otherwise panic
+func MustInit(options ...Option) {
+	err := Init(options...)
+	if err != nil {
+		log.Panic(context.Background(), "init dbo failed", log.Err(err))
+	}
+}
+
+// InitLazy create global dbo with options on first use instead of now
+func InitLazy(options ...Option) {
+	globalMutex.Lock()
+	defer globalMutex.Unlock()
+
+	lazyOptions = append(make([]Option, 0, len(options)), options...)
+}
+
 // ReplaceGlobal replace global dbo instance
 func ReplaceGlobal(dbo *DBO) {
 	globalMutex.Lock()
@@ -56,7 +122,11 @@ func GetGlobal() (*DBO, error) {
 	defer globalMutex.Unlock()
 
 	if globalDBO == nil {
-		dbo, err := New()
+		if lazyOptions == nil {
+			return nil, ErrNotInitialized
+		}
+
+		dbo, err := New(lazyOptions...)
 		if err != nil {
 			return nil, err
 		}
@@ -82,76 +152,226 @@ func NewWithConfig(options ...Option) (*DBO, error) {
 	}
 
 	ctx := context.Background()
-	var db *gorm.DB
-	var err error
-	switch config.DBType {
-	case MySQL:
-		db, err = gorm.Open(mysql.New(mysql.Config{
-			DriverName: config.DBType.DriverName(),
-			DSN:        config.ConnectionString,
-		}), &gorm.Config{QueryFields: true})
-	default:
-		log.Panic(ctx, "unsupported database type", log.String("databaseType", config.DBType.String()))
-	}
-
+	err := config.Validate()
 	if err != nil {
-		log.Warn(ctx, "init database connection failed",
+		log.Warn(ctx, "invalid dbo config",
 			log.Err(err),
-			log.String("databaseType", config.DBType.String()),
-			log.String("connectionString", config.ConnectionString))
+			log.String("databaseType", config.DBType.String()))
 		return nil, err
 	}
 
-	sqlDB, err := db.DB()
+	dsn, err := config.DSN()
 	if err != nil {
-		log.Warn(ctx, "get DB failed",
+		log.Warn(ctx, "get database connection string failed",
 			log.Err(err),
-			log.String("databaseType", config.DBType.String()),
-			log.String("connectionString", config.ConnectionString))
+			log.String("databaseType", config.DBType.String()))
 		return nil, err
 	}
 
-	err = sqlDB.Ping()
+	dbo := &DBO{
+		config:    config,
+		ready:     make(chan struct{}),
+		dsn:       dsn,
+		tracer:    newTracer(config),
+		sensitive: newSensitiveColumns(config.SensitiveColumns),
+	}
+	dbo.slowQueries = newSlowQueryMonitor(config, dsn, dbo.sensitive)
+	dbo.queryStats = newQueryStats(config.QueryStatsLimit)
+	dbo.nPlusOne = newNPlusOneDetector(config)
+	dbo.guard = newQueryGuard(config)
+	dbo.cache = newEntityCache(config, dsn)
+	dbo.tenant = newTenantScope(config)
+	for _, observer := range config.Observers {
+		dbo.AddObserver(observer)
+	}
+
+	if config.ConnectAsync {
+		// open without connecting, the first operation waits until database is ready
+		dbo.db, err = dbo.openDB(ctx, dsn, true)
+		if err != nil {
+			return nil, err
+		}
+
+		// connecting stops once dbo is closed
+		connectCtx, cancel := context.WithCancel(ctx)
+		dbo.cancelConnect = cancel
+		go func() {
+			defer cancel()
+			err := retryConnect(connectCtx, config, func() error {
+				return pingDB(connectCtx, config, dbo.db, dsn)
+			})
+			dbo.readyMutex.Lock()
+			dbo.readyErr, dbo.lastConnect = err, time.Now()
+			dbo.readyMutex.Unlock()
+			close(dbo.ready)
+		}()
+
+		return dbo, nil
+	}
+
+	err = retryConnect(ctx, config, func() error {
+		db, err := dbo.openDB(ctx, dsn, false)
+		if err != nil {
+			return err
+		}
+
+		err = pingDB(ctx, config, db, dsn)
+		if err != nil {
+			if sqlDB, err1 := db.DB(); err1 == nil {
+				sqlDB.Close()
+			}
+			return err
+		}
+
+		dbo.db = db
+		return nil
+	})
 	if err != nil {
-		log.Warn(ctx, "ping datebase failed",
-			log.Err(err),
-			log.String("databaseType", config.DBType.String()),
-			log.String("connectionString", config.ConnectionString))
 		return nil, err
 	}
+	close(dbo.ready)
+
+	return dbo, nil
+}
+
+// WaitReady wait until database is connected, or connecting failed.
+// if connecting in background failed, the database is pinged again at most once every ConnectRetryInterval
+func (s *DBO) WaitReady(ctx context.Context) error {
+	select {
+	case <-s.ready:
+		return s.reconnect(ctx)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reconnect ping database again if connecting failed and ConnectRetryInterval passed since the last attempt.
+// the lock is released while pinging, other callers get the last error meanwhile
+func (s *DBO) reconnect(ctx context.Context) error {
+	s.readyMutex.Lock()
+	if s.readyErr == nil || time.Since(s.lastConnect) < s.config.ConnectRetryInterval {
+		err := s.readyErr
+		s.readyMutex.Unlock()
+		return err
+	}
+	s.lastConnect = time.Now()
+	s.readyMutex.Unlock()
+
+	err := pingDB(ctx, s.config, s.db, s.dsn)
+
+	s.readyMutex.Lock()
+	defer s.readyMutex.Unlock()
+
+	if err != nil {
+		s.readyErr = err
+		return err
+	}
+
+	s.readyErr = nil
+	log.Info(ctx, "connect database successfully after connecting in background failed")
+	return nil
+}
 
-	if config.MaxOpenConns > 0 {
-		sqlDB.SetMaxOpenConns(config.MaxOpenConns)
+// readyError get error of connecting database, nil if connected or still connecting
+func (s *DBO) readyError() error {
+	s.readyMutex.Lock()
+	defer s.readyMutex.Unlock()
+
+	return s.readyErr
+}
+
+// Close wait for transactions in progress until ctx is done, then close database connections.
+// new transactions and db contexts are rejected with ErrClosed once closing
+func (s *DBO) Close(ctx context.Context) error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return ErrClosed
 	}
+	s.closed = true
+	s.mutex.Unlock()
 
-	if config.MaxIdleConns > 0 {
-		sqlDB.SetMaxIdleConns(config.MaxIdleConns)
+	if s.cancelConnect != nil {
+		s.cancelConnect()
 	}
 
-	if config.ConnMaxLifetime > 0 {
-		sqlDB.SetConnMaxLifetime(config.ConnMaxLifetime)
+	done := make(chan struct{})
+	go func() {
+		s.transactions.Wait()
+		close(done)
+	}()
+
+	var waitErr error
+	select {
+	case <-done:
+		log.Debug(ctx, "all transactions done before closing")
+	case <-ctx.Done():
+		waitErr = ctx.Err()
+		log.Warn(ctx, "close dbo before all transactions done", log.Err(waitErr))
 	}
 
-	if config.ConnMaxIdleTime > 0 {
-		sqlDB.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+	err := s.slowQueries.close()
+	if err != nil {
+		log.Warn(ctx, "close explain connection failed", log.Err(err))
 	}
 
-	return &DBO{db, config}, nil
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		log.Warn(ctx, "get DB failed", log.Err(err))
+		return err
+	}
+
+	err = sqlDB.Close()
+	if err != nil {
+		log.Warn(ctx, "close database connections failed", log.Err(err))
+		return err
+	}
+
+	log.Info(ctx, "dbo closed")
+
+	return waitErr
 }
 
-func (s DBO) GetDB(ctx context.Context) *DBContext {
-	ctxDB := &DBContext{DB: s.db.Session(&gorm.Session{
-		Context:     ctx,
-		NewDB:       true,
-		QueryFields: true,
-	})}
+// beginTransaction track transaction in progress, fails if dbo is closed
+func (s *DBO) beginTransaction() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
-	ctxDB.Logger = logger.New(ctxDB, logger.Config{
-		LogLevel:                  s.config.LogLevel.GormLogLevel(),
-		SlowThreshold:             s.config.SlowThreshold,
-		IgnoreRecordNotFoundError: false,
-		Colorful:                  false,
-	})
+	if s.closed {
+		return ErrClosed
+	}
+
+	s.transactions.Add(1)
+	return nil
+}
+
+// endTransaction mark transaction done
+func (s *DBO) endTransaction() {
+	s.transactions.Done()
+}
+
+func (s *DBO) isClosed() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.closed
+}
+
+// GetDB get db context, operations of db context fail if database is not ready
+func (s *DBO) GetDB(ctx context.Context) *DBContext {
+	ctxDB := &DBContext{
+		DB: s.db.Session(&gorm.Session{
+			Context:     ctx,
+			NewDB:       true,
+			QueryFields: true,
+		}),
+		dbo: s,
+	}
+
+	err := s.WaitReady(ctx)
+	if err != nil {
+		ctxDB.AddError(err)
+	}
 
 	return ctxDB
 }
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/nzai/log"
+	"gorm.io/gorm"
 )
 
 func TestMain(m *testing.M) {
@@ -24,3 +25,41 @@ func TestMain(m *testing.M) {
 
 	os.Exit(m.Run())
 }
+
+// newTestDBO create dbo of config without connecting, as NewWithConfig does, so that statements can be built
+// in dry run sessions without database
+func newTestDBO(t *testing.T, config *Config) *DBO {
+	t.Helper()
+
+	if config.ConnectionString == "" {
+		config.ConnectionString = "root:123456@tcp(127.0.0.1:3306)/testdb?parseTime=true"
+	}
+
+	dbo := &DBO{
+		config:    config,
+		ready:     make(chan struct{}),
+		dsn:       config.ConnectionString,
+		tracer:    newTracer(config),
+		sensitive: newSensitiveColumns(config.SensitiveColumns),
+		nPlusOne:  newNPlusOneDetector(config),
+		guard:     newQueryGuard(config),
+		cache:     newEntityCache(config, config.ConnectionString),
+		tenant:    newTenantScope(config),
+	}
+
+	db, err := dbo.openDB(context.Background(), dbo.dsn, true)
+	if err != nil {
+		t.Fatalf("openDB() error = %v", err)
+	}
+	dbo.db = db
+
+	return dbo
+}
+
+// dryRun get db context of dbo building statements without executing them
+func dryRun(ctx context.Context, dbo *DBO) *DBContext {
+	return &DBContext{
+		DB:  dbo.db.Session(&gorm.Session{Context: ctx, NewDB: true, DryRun: true, SkipDefaultTransaction: true}),
+		dbo: dbo,
+	}
+}
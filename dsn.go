@@ -0,0 +1,160 @@
+package dbo
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// redactedPassword replacement of password in logs
+const redactedPassword = "******"
+
+// DSN get data source name of config. ConnectionString is used if not empty, otherwise the dsn
+// is generated from MySQLConfig or the structured connection fields, with parseTime=true by default
+func (c *Config) DSN() (string, error) {
+	if c.ConnectionString != "" {
+		return c.ConnectionString, nil
+	}
+
+	mc, err := c.mysqlConfig()
+	if err != nil {
+		return "", err
+	}
+
+	if mc.TLS != nil {
+		// tls config can not be formatted into dsn, register it by name
+		name, err := registerTLSConfig(mc.TLS)
+		if err != nil {
+			return "", err
+		}
+
+		mc.TLS = nil
+		mc.TLSConfig = name
+	}
+
+	// charset and params are appended as query string, so that the driver parses known parameters such as charset.
+	// the default charset never overrides charset of MySQLConfig, params do override the same params of it
+	params := make(map[string]string, len(c.Params)+1)
+	if _, ok := mc.Params["charset"]; c.Charset != "" && !ok {
+		params["charset"] = c.Charset
+	}
+	for key, value := range c.Params {
+		delete(mc.Params, key)
+		params[key] = value
+	}
+
+	dsn := mc.FormatDSN()
+	if len(params) > 0 {
+		keys := make([]string, 0, len(params))
+		for key := range params {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		values := make([]string, 0, len(keys))
+		for _, key := range keys {
+			values = append(values, key+"="+url.QueryEscape(params[key]))
+		}
+
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		dsn += separator + strings.Join(values, "&")
+	}
+
+	_, err = mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+
+	return dsn, nil
+}
+
+// mysqlConfig get mysql driver config from MySQLConfig or the structured connection fields
+func (c *Config) mysqlConfig() (*mysql.Config, error) {
+	if c.MySQLConfig != nil {
+		return c.MySQLConfig.Clone(), nil
+	}
+
+	mc := mysql.NewConfig()
+	mc.ParseTime = true
+	mc.User = c.User
+	mc.Passwd = c.Password
+	mc.DBName = c.Database
+	mc.Collation = c.Collation
+	mc.TLS = c.TLSConfig
+	mc.Timeout = c.DialTimeout
+	mc.ReadTimeout = c.ReadTimeout
+	mc.WriteTimeout = c.WriteTimeout
+	if c.Loc != nil {
+		mc.Loc = c.Loc
+	}
+
+	if c.Host != "" {
+		mc.Net = "tcp"
+		mc.Addr = c.Host
+		if c.Port > 0 {
+			mc.Addr = net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+		}
+	}
+
+	if c.PasswordFile != "" {
+		password, err := os.ReadFile(c.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("read password file failed: %w", err)
+		}
+
+		mc.Passwd = strings.TrimSpace(string(password))
+	}
+
+	return mc, nil
+}
+
+// tlsConfigNames registered name by tls config, so that every tls config is registered once
+var tlsConfigNames sync.Map
+
+// registerTLSConfig register tls config to mysql driver, get the registered name
+func registerTLSConfig(config *tls.Config) (string, error) {
+	if name, ok := tlsConfigNames.Load(config); ok {
+		return name.(string), nil
+	}
+
+	name := fmt.Sprintf("dbo-%p", config)
+	err := mysql.RegisterTLSConfig(name, config)
+	if err != nil {
+		return "", err
+	}
+
+	tlsConfigNames.Store(config, name)
+	return name, nil
+}
+
+// RedactDSN mask password of dsn, safe to write to logs
+func RedactDSN(dsn string) string {
+	mc, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		// never log an unparsable dsn, it may contain password
+		return "<invalid dsn>"
+	}
+
+	if mc.Passwd == "" {
+		return dsn
+	}
+
+	credential := mc.User + ":" + mc.Passwd + "@"
+	if strings.HasPrefix(dsn, credential) {
+		return mc.User + ":" + redactedPassword + "@" + dsn[len(credential):]
+	}
+
+	mc.Passwd = redactedPassword
+	return mc.FormatDSN()
+}
@@ -0,0 +1,126 @@
+package dbo
+
+import (
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestRedactDSN(t *testing.T) {
+	got := RedactDSN("root:123456@tcp(127.0.0.1:3306)/testdb?parseTime=true&charset=utf8mb4")
+	want := "root:******@tcp(127.0.0.1:3306)/testdb?parseTime=true&charset=utf8mb4"
+	if got != want {
+		t.Errorf("RedactDSN() = %v, want %v", got, want)
+	}
+
+	got = RedactDSN("root:123456@bad")
+	if strings.Contains(got, "123456") {
+		t.Errorf("RedactDSN() = %v", got)
+	}
+}
+
+// mysqlConfigWithParams mysql config of testdb with params
+func mysqlConfigWithParams(params map[string]string) *mysql.Config {
+	mc := mysql.NewConfig()
+	mc.User = "root"
+	mc.Net = "tcp"
+	mc.Addr = "127.0.0.1:3306"
+	mc.DBName = "testdb"
+	mc.Params = params
+	return mc
+}
+
+func TestConfigDSN(t *testing.T) {
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	err := os.WriteFile(passwordFile, []byte("123456\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		options []Option
+		want    string
+	}{
+		{
+			name: "password file",
+			options: []Option{
+				WithUser("root"),
+				WithPasswordFile(passwordFile),
+				WithHost("127.0.0.1:3306"),
+				WithDatabase("testdb"),
+				WithParams(map[string]string{"sql_mode": "'STRICT_ALL_TABLES'"}),
+			},
+			want: "root:123456@tcp(127.0.0.1:3306)/testdb?parseTime=true&charset=utf8mb4&sql_mode=%27STRICT_ALL_TABLES%27",
+		},
+		{
+			name: "structured",
+			options: []Option{
+				WithUser("root"),
+				WithPassword("123456"),
+				WithHost("127.0.0.1"),
+				WithPort(3306),
+				WithDatabase("testdb"),
+				WithTimeouts(time.Second, 0, 0),
+			},
+			want: "root:123456@tcp(127.0.0.1:3306)/testdb?parseTime=true&timeout=1s&charset=utf8mb4",
+		},
+		{
+			name: "mysql config with charset",
+			options: []Option{
+				WithMySQLConfig(mysqlConfigWithParams(map[string]string{"charset": "latin1", "sql_mode": "ANSI"})),
+				WithParams(map[string]string{"sql_mode": "TRADITIONAL"}),
+			},
+			want: "root@tcp(127.0.0.1:3306)/testdb?charset=latin1&sql_mode=TRADITIONAL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := getDefaultConfig()
+			for _, option := range tt.options {
+				option(config)
+			}
+
+			got, err := config.DSN()
+			if err != nil {
+				t.Fatalf("DSN() failed due to %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("DSN() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigDSNTLS(t *testing.T) {
+	config := getDefaultConfig()
+	WithHost("127.0.0.1")(config)
+	WithTLSConfig(&tls.Config{ServerName: "db.internal"})(config)
+
+	first, err := config.DSN()
+	if err != nil {
+		t.Fatalf("DSN() failed due to %v", err)
+	}
+
+	// the tls config is registered once, every dsn of the config refers to the same name
+	second, _ := config.DSN()
+	if first != second || !strings.Contains(first, "tls=dbo-") {
+		t.Errorf("DSN() = %s then %s, want the same registered tls config", first, second)
+	}
+
+	registered := 0
+	tlsConfigNames.Range(func(key, value any) bool {
+		registered++
+		return true
+	})
+	if registered != 1 {
+		t.Errorf("registered tls configs = %d, want 1", registered)
+	}
+}
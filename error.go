@@ -1,6 +1,17 @@
 package dbo
 
-import "errors"
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
 
 var (
 	// ErrRecordNotFound record not found
@@ -9,4 +20,218 @@ var (
 	ErrDuplicateRecord = errors.New("duplicate record")
 	// ErrExceededLimit exceeded limit
 	ErrExceededLimit = errors.New("exceeded limit")
+	// ErrInvalidPrimaryKey primary key is missing or does not match the keys given
+	ErrInvalidPrimaryKey = errors.New("invalid primary key")
+	// ErrLockOutsideTransaction lock options used outside a transaction
+	ErrLockOutsideTransaction = errors.New("lock options are only available in transaction")
+	// ErrInvalidParameter invalid sql parameters
+	ErrInvalidParameter = errors.New("invalid parameter")
+	// ErrForeignKeyViolation foreign key constraint fails, mysql error 1451/1452
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+	// ErrDataTooLong data too long for column, mysql error 1406
+	ErrDataTooLong = errors.New("data too long")
+	// ErrDeadlock deadlock found when trying to get lock, mysql error 1213
+	ErrDeadlock = errors.New("deadlock")
+	// ErrLockWaitTimeout lock wait timeout exceeded, mysql error 1205
+	ErrLockWaitTimeout = errors.New("lock wait timeout")
+	// ErrReadOnly database is running with read only option, mysql error 1290
+	ErrReadOnly = errors.New("database is read only")
+	// ErrConnection connection to database is lost or broken
+	ErrConnection = errors.New("database connection error")
+	// ErrNotInitialized global dbo is used before Init
+	ErrNotInitialized = errors.New("dbo is not initialized")
+	// ErrClosed dbo is closed
+	ErrClosed = errors.New("dbo is closed")
+	// ErrNPlusOneQuery statement is executed too many times within a context, see WithNPlusOneDetection
+	ErrNPlusOneQuery = errors.New("n+1 query")
+	// ErrUnsafeQuery statement is rejected by query guard, see WithQueryGuard
+	ErrUnsafeQuery = errors.New("unsafe query")
+	// ErrTenantRequired tenant entity is accessed without tenant in context, see WithTenantResolver and WithoutTenant
+	ErrTenantRequired = errors.New("tenant required")
+	// ErrTenantMismatch entity belongs to another tenant than the context
+	ErrTenantMismatch = errors.New("tenant mismatch")
 )
+
+// duplicateKeyRegex match key name of mysql error 1062, example: Duplicate entry 'a' for key 'table_a.uniq_name'.
+// anchored at the end, the duplicate entry may contain "for key" too
+var duplicateKeyRegex = regexp.MustCompile(`for key '([^']+)'$`)
+
+// OperationError database error with operation details, returned by every helper. errors.Is matches both the
+// classified sentinel error such as ErrDuplicateRecord and the original driver error, comparing returned errors
+// with sentinel errors by == does not match any more. it is not named Error, which is the log level
+type OperationError struct {
+	// Op operation name, such as insert or query
+	Op string
+	// Table table name
+	Table string
+	// Duration duration of the operation
+	Duration time.Duration
+	// Key violated key name of duplicate record
+	Key string
+	// Kind classified sentinel error, nil if the error is not classified
+	Kind error
+	// Err original error
+	Err error
+}
+
+func (e *OperationError) Error() string {
+	var builder strings.Builder
+	builder.WriteString("dbo ")
+	builder.WriteString(e.Op)
+	if e.Table != "" {
+		builder.WriteString(" ")
+		builder.WriteString(e.Table)
+	}
+	builder.WriteString(": ")
+
+	if e.Kind == nil {
+		builder.WriteString(e.Err.Error())
+		return builder.String()
+	}
+
+	builder.WriteString(e.Kind.Error())
+	if e.Key != "" {
+		builder.WriteString(" (key ")
+		builder.WriteString(e.Key)
+		builder.WriteString(")")
+	}
+
+	if e.Err.Error() != e.Kind.Error() {
+		builder.WriteString(": ")
+		builder.WriteString(e.Err.Error())
+	}
+
+	return builder.String()
+}
+
+func (e *OperationError) Unwrap() []error {
+	if e.Kind == nil {
+		return []error{e.Err}
+	}
+
+	return []error{e.Kind, e.Err}
+}
+
+// IsRetryable check whether the operation may succeed if retried, such as deadlock, lock wait timeout and connection error
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrDeadlock) ||
+		errors.Is(err, ErrLockWaitTimeout) ||
+		errors.Is(err, ErrConnection)
+}
+
+// errorTypes low cardinality names of sentinel errors, checked in order
+var errorTypes = []struct {
+	err  error
+	name string
+}{
+	{ErrRecordNotFound, "not_found"},
+	{ErrDuplicateRecord, "duplicate"},
+	{ErrForeignKeyViolation, "foreign_key"},
+	{ErrDataTooLong, "data_too_long"},
+	{ErrDeadlock, "deadlock"},
+	{ErrLockWaitTimeout, "lock_wait_timeout"},
+	{ErrReadOnly, "read_only"},
+	{ErrConnection, "connection"},
+	{ErrInvalidPrimaryKey, "invalid_primary_key"},
+	{ErrInvalidParameter, "invalid_parameter"},
+	{ErrLockOutsideTransaction, "lock_outside_transaction"},
+	{ErrNotInitialized, "not_initialized"},
+	{ErrClosed, "closed"},
+	{ErrNPlusOneQuery, "n_plus_one"},
+	{ErrUnsafeQuery, "unsafe_query"},
+	{ErrTenantRequired, "tenant_required"},
+	{ErrTenantMismatch, "tenant_mismatch"},
+	{context.DeadlineExceeded, "timeout"},
+	{context.Canceled, "canceled"},
+}
+
+// ErrorType low cardinality type name of err for metrics and tracing, such as duplicate or deadlock.
+// empty if err is nil, other if err is not classified
+func ErrorType(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	for _, errorType := range errorTypes {
+		if errors.Is(err, errorType.err) {
+			return errorType.name
+		}
+	}
+
+	return "other"
+}
+
+// newError classify database error and attach operation details, nil if err is nil
+func newError(op, table string, start time.Time, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var e *OperationError
+	if errors.As(err, &e) {
+		return err
+	}
+
+	kind, key := classifyError(err)
+	return &OperationError{
+		Op:       op,
+		Table:    table,
+		Duration: time.Since(start),
+		Key:      key,
+		Kind:     kind,
+		Err:      err,
+	}
+}
+
+// classifyError get sentinel error of err, and the violated key name for duplicate record
+func classifyError(err error) (error, string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrRecordNotFound, ""
+	}
+
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrDuplicateRecord, ""
+	}
+
+	var me *mysql.MySQLError
+	if errors.As(err, &me) {
+		switch me.Number {
+		case 1062, 1586:
+			matches := duplicateKeyRegex.FindStringSubmatch(me.Message)
+			if len(matches) > 1 {
+				return ErrDuplicateRecord, matches[1]
+			}
+			return ErrDuplicateRecord, ""
+		case 1216, 1217, 1451, 1452:
+			return ErrForeignKeyViolation, ""
+		case 1406:
+			return ErrDataTooLong, ""
+		case 1213:
+			return ErrDeadlock, ""
+		case 1205:
+			return ErrLockWaitTimeout, ""
+		case 1290, 1792, 1836:
+			return ErrReadOnly, ""
+		case 1053, 1152, 1153, 1159, 1161, 2006, 2013:
+			return ErrConnection, ""
+		default:
+			return nil, ""
+		}
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) {
+		return ErrConnection, ""
+	}
+
+	// context.DeadlineExceeded implements net.Error, timeout and cancellation of context are not connection errors
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return nil, ""
+	}
+
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return ErrConnection, ""
+	}
+
+	return nil, ""
+}
@@ -0,0 +1,124 @@
+package dbo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestNewError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantIs  error
+		wantKey string
+	}{
+		{
+			name:    "duplicate",
+			err:     &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a' for key 'table_a.uniq_name'"},
+			wantIs:  ErrDuplicateRecord,
+			wantKey: "table_a.uniq_name",
+		},
+		{
+			name:    "duplicate entry containing key",
+			err:     &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'x for key 'other'' for key 'table_a.uniq_name'"},
+			wantIs:  ErrDuplicateRecord,
+			wantKey: "table_a.uniq_name",
+		},
+		{
+			name:   "foreign key",
+			err:    &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row"},
+			wantIs: ErrForeignKeyViolation,
+		},
+		{
+			name:   "deadlock",
+			err:    fmt.Errorf("wrapped: %w", &mysql.MySQLError{Number: 1213}),
+			wantIs: ErrDeadlock,
+		},
+		{
+			name:   "not found",
+			err:    gorm.ErrRecordNotFound,
+			wantIs: ErrRecordNotFound,
+		},
+		{
+			name:   "connection",
+			err:    mysql.ErrInvalidConn,
+			wantIs: ErrConnection,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newError(OpInsert, "table_a", time.Now(), tt.err)
+			if !errors.Is(err, tt.wantIs) {
+				t.Errorf("newError() = %v, want %v", err, tt.wantIs)
+			}
+
+			if !errors.Is(err, tt.err) {
+				t.Errorf("newError() = %v, should wrap %v", err, tt.err)
+			}
+
+			var oe *OperationError
+			if !errors.As(err, &oe) {
+				t.Fatalf("newError() = %T, want *OperationError", err)
+			}
+
+			if oe.Op != OpInsert || oe.Table != "table_a" || oe.Key != tt.wantKey {
+				t.Errorf("newError() = %+v", oe)
+			}
+		})
+	}
+}
+
+func TestErrorType(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{newError(OpInsert, "table_a", time.Now(), &mysql.MySQLError{Number: 1062}), "duplicate"},
+		{newError(OpUpdate, "table_a", time.Now(), &mysql.MySQLError{Number: 1213}), "deadlock"},
+		{fmt.Errorf("wrapped: %w", context.DeadlineExceeded), "timeout"},
+		// context.DeadlineExceeded implements net.Error, but is not a connection error
+		{newError(OpGet, "table_a", time.Now(), context.DeadlineExceeded), "timeout"},
+		{newError(OpGet, "table_a", time.Now(), context.Canceled), "canceled"},
+		{errors.New("unknown"), "other"},
+	}
+
+	for _, tt := range tests {
+		if got := ErrorType(tt.err); got != tt.want {
+			t.Errorf("ErrorType(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+type operationRecorder struct {
+	events []OperationEvent
+}
+
+func (r *operationRecorder) ObserveOperation(ctx context.Context, event OperationEvent) {
+	r.events = append(r.events, event)
+}
+
+func (r *operationRecorder) ObserveTransaction(ctx context.Context, event TransactionEvent) {}
+
+func TestInvalidKeyObserved(t *testing.T) {
+	ctx := context.Background()
+	dbo := newTestDBO(t, getDefaultConfig())
+	recorder := &operationRecorder{}
+	dbo.AddObserver(recorder)
+
+	_, err := GetByKeyTx[tableA](ctx, dryRun(ctx, dbo), 1, 2)
+	if !errors.Is(err, ErrInvalidPrimaryKey) {
+		t.Fatalf("GetByKeyTx() error = %v, want %v", err, ErrInvalidPrimaryKey)
+	}
+
+	if len(recorder.events) != 1 || !errors.Is(recorder.events[0].Err, ErrInvalidPrimaryKey) {
+		t.Errorf("events = %+v, want failed get", recorder.events)
+	}
+}
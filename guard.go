@@ -0,0 +1,181 @@
+package dbo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nzai/log"
+	"github.com/pingcap/tidb/pkg/parser"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	// tableRowsTTL duration to cache estimated row count of tables
+	tableRowsTTL = 10 * time.Minute
+	// fullScansLimit max fingerprints to cache plan of
+	fullScansLimit = 1000
+)
+
+type tableRows struct {
+	rows    int64
+	expires time.Time
+}
+
+// queryGuard check statements of QueryTx and CountTx before executing them
+type queryGuard struct {
+	minRows int64
+	explain bool
+	reject  bool
+
+	mutex sync.Mutex
+	// tables estimated row count by table name
+	tables map[string]tableRows
+	// fullScans whether the plan of a fingerprint has full table scan, by digest. at most fullScansLimit
+	// fingerprints are kept
+	fullScans map[string]bool
+}
+
+// newQueryGuard create guard, nil if neither min rows nor explain is configured
+func newQueryGuard(config *Config) *queryGuard {
+	if config.QueryGuardMinRows <= 0 && !config.QueryGuardExplain {
+		return nil
+	}
+
+	return &queryGuard{
+		minRows:   config.QueryGuardMinRows,
+		explain:   config.QueryGuardExplain,
+		reject:    config.QueryGuardReject,
+		tables:    make(map[string]tableRows),
+		fullScans: make(map[string]bool),
+	}
+}
+
+// guard check the statement built by build, return ErrUnsafeQuery if it is unsafe in reject mode,
+// otherwise unsafe statements are logged only
+func (s *DBContext) guard(ctx context.Context, op, table string, hasWhere bool, build func(tx *gorm.DB) *gorm.DB) error {
+	if s.dbo == nil || s.dbo.guard == nil {
+		return nil
+	}
+
+	g := s.dbo.guard
+	reason, err := g.check(ctx, s, table, hasWhere, build)
+	if err != nil {
+		log.Warn(ctx, "check query safety failed",
+			log.Err(err),
+			log.String("operation", op),
+			log.String("tableName", table))
+		return nil
+	}
+
+	if reason == "" {
+		return nil
+	}
+
+	if g.reject {
+		err = fmt.Errorf("%w: %s %s: %s", ErrUnsafeQuery, op, table, reason)
+		log.Warn(ctx, "unsafe query rejected", log.Err(err))
+		return err
+	}
+
+	log.Warn(ctx, "unsafe query",
+		log.String("operation", op),
+		log.String("tableName", table),
+		log.String("reason", reason),
+		log.String("caller", caller()))
+	return nil
+}
+
+// check get the reason why statement is unsafe, empty if it is safe. probes are executed on the connection of db
+// without callbacks, so that they are neither logged, traced nor counted as statements of the caller
+func (g *queryGuard) check(ctx context.Context, db *DBContext, table string, hasWhere bool, build func(tx *gorm.DB) *gorm.DB) (string, error) {
+	if !hasWhere && g.minRows > 0 {
+		rows, err := g.tableRows(ctx, db, table)
+		if err != nil {
+			return "", err
+		}
+
+		if rows >= g.minRows {
+			return fmt.Sprintf("no where clause on table with about %d rows", rows), nil
+		}
+	}
+
+	if !g.explain {
+		return "", nil
+	}
+
+	stmt := build(db.Session(&gorm.Session{DryRun: true, SkipHooks: true, Logger: logger.Discard})).Statement
+	if stmt.Error != nil {
+		return "", stmt.Error
+	}
+
+	statement := stmt.SQL.String()
+	_, digest := parser.NormalizeDigest(statement)
+	g.mutex.Lock()
+	fullScan, ok := g.fullScans[digest.String()]
+	g.mutex.Unlock()
+	if !ok {
+		rows, err := db.Statement.ConnPool.QueryContext(ctx, "EXPLAIN "+statement, stmt.Vars...)
+		if err != nil {
+			return "", err
+		}
+
+		plan, err := scanExplainRows(rows)
+		if err != nil {
+			return "", err
+		}
+
+		fullScan, _ = analyzePlan(plan)
+		g.storeFullScan(digest.String(), fullScan)
+	}
+
+	if fullScan {
+		return "explain shows full table scan (type=ALL)", nil
+	}
+
+	return "", nil
+}
+
+// storeFullScan cache whether the plan of digest has full table scan, an arbitrary fingerprint is evicted
+// beyond fullScansLimit, its plan is explained again when needed
+func (g *queryGuard) storeFullScan(digest string, fullScan bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if _, ok := g.fullScans[digest]; !ok && len(g.fullScans) >= fullScansLimit {
+		for key := range g.fullScans {
+			delete(g.fullScans, key)
+			break
+		}
+	}
+
+	g.fullScans[digest] = fullScan
+}
+
+// tableRows get estimated row count of table from information_schema, cached for tableRowsTTL
+func (g *queryGuard) tableRows(ctx context.Context, db *DBContext, table string) (int64, error) {
+	g.mutex.Lock()
+	cached, ok := g.tables[table]
+	g.mutex.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.rows, nil
+	}
+
+	var rows int64
+	err := db.Statement.ConnPool.
+		QueryRowContext(ctx, "SELECT COALESCE(TABLE_ROWS, 0) FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", table).
+		Scan(&rows)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	g.mutex.Lock()
+	g.tables[table] = tableRows{rows: rows, expires: time.Now().Add(tableRowsTTL)}
+	g.mutex.Unlock()
+
+	return rows, nil
+}
@@ -0,0 +1,57 @@
+package dbo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNewQueryGuard(t *testing.T) {
+	if newQueryGuard(&Config{}) != nil {
+		t.Error("guard should be disabled without min rows and explain")
+	}
+
+	if newQueryGuard(&Config{QueryGuardMinRows: 1000}) == nil {
+		t.Error("guard should be enabled with min rows")
+	}
+
+	guard := newQueryGuard(&Config{QueryGuardExplain: true})
+	for index := 0; index < fullScansLimit+10; index++ {
+		guard.storeFullScan(fmt.Sprint(index), index%2 == 0)
+	}
+	if len(guard.fullScans) != fullScansLimit {
+		t.Errorf("fullScans = %d, want %d", len(guard.fullScans), fullScansLimit)
+	}
+}
+
+func TestQueryGuardMissingWhere(t *testing.T) {
+	ctx := context.Background()
+	expires := time.Now().Add(time.Minute)
+	for _, reject := range []bool{true, false} {
+		guard := newQueryGuard(&Config{QueryGuardMinRows: 1000, QueryGuardReject: reject})
+		// cached row counts, so that no database is needed
+		guard.tables["big"] = tableRows{rows: 5000, expires: expires}
+		guard.tables["small"] = tableRows{rows: 10, expires: expires}
+		db := &DBContext{dbo: &DBO{guard: guard}}
+
+		err := db.guard(ctx, OpQuery, "big", false, nil)
+		if reject && !errors.Is(err, ErrUnsafeQuery) {
+			t.Errorf("query without where on big table should be rejected, got %v", err)
+		}
+		if !reject && err != nil {
+			t.Errorf("query without where should be logged only, got %v", err)
+		}
+
+		err = db.guard(ctx, OpQuery, "big", true, nil)
+		if err != nil {
+			t.Errorf("query with where should be allowed, got %v", err)
+		}
+
+		err = db.guard(ctx, OpCount, "small", false, nil)
+		if err != nil {
+			t.Errorf("query without where on small table should be allowed, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package dbo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/nzai/log"
+)
+
+// HealthStatus verdict of health check
+type HealthStatus string
+
+const (
+	// HealthReady database is available
+	HealthReady HealthStatus = "ready"
+	// HealthDegraded database is available but slow or the connection pool is exhausted
+	HealthDegraded HealthStatus = "degraded"
+	// HealthDown database is unavailable
+	HealthDown HealthStatus = "down"
+)
+
+// HealthReport result of health check
+type HealthReport struct {
+	Status  HealthStatus  `json:"status"`
+	Latency time.Duration `json:"-"`
+	Reasons []string      `json:"reasons,omitempty"`
+	Error   string        `json:"error,omitempty"`
+	Stats   sql.DBStats   `json:"stats"`
+}
+
+// MarshalJSON marshal latency as readable duration
+func (r HealthReport) MarshalJSON() ([]byte, error) {
+	type report HealthReport
+	return json.Marshal(struct {
+		report
+		Latency string `json:"latency"`
+	}{report(r), r.Latency.String()})
+}
+
+// Stats get connection pool statistics
+func (s *DBO) Stats() sql.DBStats {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return sql.DBStats{}
+	}
+
+	return sqlDB.Stats()
+}
+
+// Health ping database and check connection pool. the status is degraded if ping latency exceeds
+// SlowThreshold, all connections are in use, or connections were waited since the last check.
+// dbo connects to a single database without replicas, so the report has no replica status
+func (s *DBO) Health(ctx context.Context) *HealthReport {
+	report := &HealthReport{Status: HealthReady, Stats: s.Stats()}
+	down := func(reason string, err error) *HealthReport {
+		report.Status = HealthDown
+		report.Reasons = append(report.Reasons, reason)
+		if err != nil {
+			report.Error = err.Error()
+		}
+
+		log.Warn(ctx, "database is down", log.String("reason", reason), log.Err(err))
+		return report
+	}
+
+	if s.isClosed() {
+		return down("dbo is closed", ErrClosed)
+	}
+
+	select {
+	case <-s.ready:
+		if err := s.readyError(); err != nil {
+			return down("connect database failed", err)
+		}
+	default:
+		return down("database is connecting", nil)
+	}
+
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return down("get DB failed", err)
+	}
+
+	start := time.Now()
+	err = sqlDB.PingContext(ctx)
+	report.Latency = time.Since(start)
+	if err != nil {
+		return down("ping database failed", err)
+	}
+
+	if s.config.SlowThreshold > 0 && report.Latency > s.config.SlowThreshold {
+		report.Reasons = append(report.Reasons, "ping latency exceeds slow threshold")
+	}
+
+	if report.Stats.MaxOpenConnections > 0 && report.Stats.InUse >= report.Stats.MaxOpenConnections {
+		report.Reasons = append(report.Reasons, "all connections are in use")
+	}
+
+	lastWaitCount := s.lastWaitCount.Swap(report.Stats.WaitCount)
+	if report.Stats.WaitCount > lastWaitCount {
+		report.Reasons = append(report.Reasons, "connections were waited since last check")
+	}
+
+	if len(report.Reasons) > 0 {
+		report.Status = HealthDegraded
+		log.Warn(ctx, "database is degraded",
+			log.Strings("reasons", report.Reasons),
+			log.Duration("latency", report.Latency),
+			log.Any("stats", report.Stats))
+	}
+
+	return report
+}
+
+// HealthHandler http handler of health check, responds the health report as json,
+// with status code 503 if database is down, otherwise 200
+func (s *DBO) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := s.Health(r.Context())
+
+		w.Header().Set("Content-Type", "application/json")
+		if report.Status == HealthDown {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+
+		err := json.NewEncoder(w).Encode(report)
+		if err != nil {
+			log.Warn(r.Context(), "write health report failed", log.Err(err))
+		}
+	})
+}
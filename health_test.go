@@ -0,0 +1,109 @@
+package dbo
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// pingConnector connector of connections which only support ping, so that no database is needed
+type pingConnector struct {
+	delay time.Duration
+}
+
+func (c pingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return pingConn(c), nil
+}
+
+func (c pingConnector) Driver() driver.Driver {
+	return nil
+}
+
+type pingConn struct {
+	delay time.Duration
+}
+
+func (c pingConn) Ping(ctx context.Context) error {
+	time.Sleep(c.delay)
+	return nil
+}
+
+func (c pingConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c pingConn) Close() error {
+	return nil
+}
+
+func (c pingConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+// newPingDBO create connected dbo of connections pinged after delay
+func newPingDBO(t *testing.T, delay time.Duration) *DBO {
+	config := getDefaultConfig()
+	config.SlowThreshold = 10 * time.Millisecond
+
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: sql.OpenDB(pingConnector{delay: delay}), SkipInitializeWithVersion: true}),
+		&gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("open db failed due to %v", err)
+	}
+
+	dbo := &DBO{db: db, config: config, ready: make(chan struct{})}
+	close(dbo.ready)
+	return dbo
+}
+
+func TestHealthHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		delay      time.Duration
+		closed     bool
+		wantStatus HealthStatus
+		wantCode   int
+	}{
+		{name: "healthy", wantStatus: HealthReady, wantCode: http.StatusOK},
+		{name: "degraded", delay: 50 * time.Millisecond, wantStatus: HealthDegraded, wantCode: http.StatusOK},
+		{name: "closed", closed: true, wantStatus: HealthDown, wantCode: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbo := newPingDBO(t, tt.delay)
+			if tt.closed {
+				err := dbo.Close(context.Background())
+				if err != nil {
+					t.Fatalf("Close() failed due to %v", err)
+				}
+			}
+
+			report := dbo.Health(context.Background())
+			if report.Status != tt.wantStatus {
+				t.Errorf("Health() = %s %v, want %s", report.Status, report.Reasons, tt.wantStatus)
+			}
+
+			recorder := httptest.NewRecorder()
+			dbo.HealthHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+			if recorder.Code != tt.wantCode || recorder.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("HealthHandler() = %d %s, want %d", recorder.Code, recorder.Header().Get("Content-Type"), tt.wantCode)
+			}
+
+			var body map[string]any
+			err := json.Unmarshal(recorder.Body.Bytes(), &body)
+			if err != nil || body["status"] != string(tt.wantStatus) || body["latency"] == nil {
+				t.Errorf("HealthHandler() body = %s, %v", recorder.Body.String(), err)
+			}
+		})
+	}
+}
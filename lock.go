@@ -0,0 +1,55 @@
+package dbo
+
+import (
+	"gorm.io/gorm/clause"
+)
+
+// LockOption row-level locking option of select statement, available in transaction only
+type LockOption func(*clause.Locking)
+
+// ForUpdate select ... for update
+func ForUpdate() LockOption {
+	return func(l *clause.Locking) {
+		l.Strength = clause.LockingStrengthUpdate
+	}
+}
+
+// ForShare select ... for share
+func ForShare() LockOption {
+	return func(l *clause.Locking) {
+		l.Strength = clause.LockingStrengthShare
+	}
+}
+
+// NoWait fail immediately instead of waiting for locked rows, implies ForUpdate unless ForShare is given
+func NoWait() LockOption {
+	return func(l *clause.Locking) {
+		l.Options = clause.LockingOptionsNoWait
+	}
+}
+
+// SkipLocked skip locked rows, implies ForUpdate unless ForShare is given
+func SkipLocked() LockOption {
+	return func(l *clause.Locking) {
+		l.Options = clause.LockingOptionsSkipLocked
+	}
+}
+
+// applyLockOptions add locking clause to db
+func (s *DBContext) applyLockOptions(options []LockOption) error {
+	if len(options) == 0 {
+		return nil
+	}
+
+	if !s.InTransaction() {
+		return ErrLockOutsideTransaction
+	}
+
+	locking := clause.Locking{Strength: clause.LockingStrengthUpdate}
+	for _, option := range options {
+		option(&locking)
+	}
+
+	s.DB = s.Clauses(locking)
+	return nil
+}
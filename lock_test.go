@@ -0,0 +1,67 @@
+package dbo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// dryRunTx transaction of dry run sessions, statements are never executed
+type dryRunTx struct {
+	gorm.ConnPool
+}
+
+func (dryRunTx) Commit() error {
+	return nil
+}
+
+func (dryRunTx) Rollback() error {
+	return nil
+}
+
+func TestApplyLockOptions(t *testing.T) {
+	ctx := context.Background()
+	dbo := newTestDBO(t, getDefaultConfig())
+
+	err := dryRun(ctx, dbo).applyLockOptions([]LockOption{ForUpdate()})
+	if !errors.Is(err, ErrLockOutsideTransaction) {
+		t.Errorf("applyLockOptions() outside transaction error = %v, want %v", err, ErrLockOutsideTransaction)
+	}
+
+	_, err = GetTx[tableA](ctx, dryRun(ctx, dbo), 1, ForShare())
+	if !errors.Is(err, ErrLockOutsideTransaction) {
+		t.Errorf("GetTx() outside transaction error = %v, want %v", err, ErrLockOutsideTransaction)
+	}
+
+	tests := []struct {
+		name    string
+		options []LockOption
+		want    string
+	}{
+		{name: "none", want: "FROM `table_a`"},
+		{name: "for update", options: []LockOption{ForUpdate()}, want: "FROM `table_a` FOR UPDATE"},
+		{name: "for share", options: []LockOption{ForShare()}, want: "FROM `table_a` FOR SHARE"},
+		{name: "no wait", options: []LockOption{NoWait()}, want: "FROM `table_a` FOR UPDATE NOWAIT"},
+		{name: "share skip locked", options: []LockOption{ForShare(), SkipLocked()}, want: "FROM `table_a` FOR SHARE SKIP LOCKED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dryRun(ctx, dbo)
+			db.Statement.ConnPool = dryRunTx{ConnPool: db.Statement.ConnPool}
+
+			err := db.applyLockOptions(tt.options)
+			if err != nil {
+				t.Fatalf("applyLockOptions() error = %v", err)
+			}
+
+			statement := db.Find(&[]tableA{}).Statement.SQL.String()
+			if !strings.HasSuffix(statement, tt.want) {
+				t.Errorf("statement = %s, want suffix %s", statement, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package dbo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/nzai/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// sqlLogger gorm logger writes sql logs with typed fields.
+// failed sql and slow sql are logged as warnings, other sql are logged as debug and sampled
+type sqlLogger struct {
+	level         logger.LogLevel
+	slowThreshold time.Duration
+	sampling      int64
+	// monitoredSlow slow sql are logged with their plans by the default handler of slow query monitor,
+	// so that they are not warned twice
+	monitoredSlow bool
+	// counter count of debug sql, shared by loggers of different levels
+	counter   *atomic.Int64
+	sensitive sensitiveColumns
+}
+
+func newSQLLogger(config *Config) *sqlLogger {
+	return &sqlLogger{
+		level:         config.LogLevel.GormLogLevel(),
+		slowThreshold: config.SlowThreshold,
+		sampling:      int64(config.SQLLogSampling),
+		monitoredSlow: config.ExplainSlowQueries && config.SlowQueryHandler == nil,
+		counter:       new(atomic.Int64),
+		sensitive:     newSensitiveColumns(config.SensitiveColumns),
+	}
+}
+
+// LogMode implements logger.Interface
+func (l *sqlLogger) LogMode(level logger.LogLevel) logger.Interface {
+	newLogger := *l
+	newLogger.level = level
+	return &newLogger
+}
+
+// ParamsFilter implements gorm.ParamsFilter, mask parameters bound to sensitive columns
+func (l *sqlLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, l.sensitive.filterParams(sql, params)
+}
+
+// Info implements logger.Interface
+func (l *sqlLogger) Info(ctx context.Context, message string, data ...interface{}) {
+	if l.level >= logger.Info {
+		log.Info(ctx, fmt.Sprintf(message, data...), log.String("logType", "sql"))
+	}
+}
+
+// Warn implements logger.Interface
+func (l *sqlLogger) Warn(ctx context.Context, message string, data ...interface{}) {
+	if l.level >= logger.Warn {
+		log.Warn(ctx, fmt.Sprintf(message, data...), log.String("logType", "sql"))
+	}
+}
+
+// Error implements logger.Interface
+func (l *sqlLogger) Error(ctx context.Context, message string, data ...interface{}) {
+	if l.level >= logger.Error {
+		log.Warn(ctx, fmt.Sprintf(message, data...), log.String("logType", "sql"))
+	}
+}
+
+// Trace implements logger.Interface, fc is called only if the sql is logged
+func (l *sqlLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	duration := time.Since(begin)
+	slow := l.slowThreshold > 0 && duration > l.slowThreshold
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+	switch {
+	case failed && l.level >= logger.Error:
+		// failures are warned by dbo helpers with their operation, the sql is logged for debugging only
+		sql, rows := fc()
+		log.Debug(ctx, "sql failed", l.fields(sql, rows, duration, slow, err)...)
+	case slow && l.level >= logger.Warn && !l.monitoredSlow:
+		sql, rows := fc()
+		log.Warn(ctx, "slow sql", l.fields(sql, rows, duration, slow, err)...)
+	case !failed && l.level >= logger.Info && l.sample():
+		sql, rows := fc()
+		log.Debug(ctx, "sql executed", l.fields(sql, rows, duration, slow, err)...)
+	}
+}
+
+func (l *sqlLogger) fields(sql string, rows int64, duration time.Duration, slow bool, err error) []log.Field {
+	fields := []log.Field{
+		log.String("logType", "sql"),
+		log.String("sql", sql),
+		log.Int64("rowsAffected", rows),
+		log.Duration("duration", duration),
+		log.String("caller", caller()),
+		log.Bool("slow", slow),
+	}
+	if err != nil {
+		fields = append(fields, log.Err(err))
+	}
+
+	return fields
+}
+
+// sample check whether debug sql should be logged, one of every sampling sql is logged
+func (l *sqlLogger) sample() bool {
+	if l.sampling <= 1 {
+		return true
+	}
+
+	return (l.counter.Add(1)-1)%l.sampling == 0
+}
+
+// caller file and line of the first frame outside gorm and dbo, which is usually the code calling dbo helpers
+func caller() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		internal := strings.HasPrefix(frame.Function, "gorm.io/") ||
+			strings.HasPrefix(frame.Function, "github.com/nzai/dbo/v2")
+		if !internal || strings.HasSuffix(frame.File, "_test.go") {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+
+		if !more {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package dbo
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSQLLoggerSample(t *testing.T) {
+	config := getDefaultConfig()
+	config.SQLLogSampling = 3
+	l := newSQLLogger(config)
+
+	logged := 0
+	for i := 0; i < 9; i++ {
+		if l.sample() {
+			logged++
+		}
+	}
+
+	if logged != 3 {
+		t.Errorf("sample() logged %d of 9, want 3", logged)
+	}
+}
+
+func TestCaller(t *testing.T) {
+	if got := caller(); !strings.Contains(got, "logger_test.go") {
+		t.Errorf("caller() = %q, want logger_test.go", got)
+	}
+}
+
+func TestSQLLoggerSlow(t *testing.T) {
+	for _, explain := range []bool{false, true} {
+		config := getDefaultConfig()
+		config.LogLevel = Warn
+		config.SlowThreshold = 10 * time.Millisecond
+		config.ExplainSlowQueries = explain
+		l := newSQLLogger(config)
+
+		logged := 0
+		l.Trace(context.Background(), time.Now().Add(-time.Second), func() (string, int64) {
+			logged++
+			return "SELECT * FROM `table_a`", 0
+		}, nil)
+
+		// slow sql are logged once with plans by the slow query monitor if explain is enabled
+		if want := map[bool]int{false: 1, true: 0}[explain]; logged != want {
+			t.Errorf("Trace() of slow sql with explain %v logged %d, want %d", explain, logged, want)
+		}
+	}
+}
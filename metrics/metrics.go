@@ -0,0 +1,173 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/nzai/dbo/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector prometheus collector of dbo operations, transactions and connection pool.
+// labels are limited to operation, table, classified error type and transaction result to keep cardinality low
+type Collector struct {
+	stats func() sql.DBStats
+
+	operationDuration   *prometheus.HistogramVec
+	operationErrors     *prometheus.CounterVec
+	transactionDuration *prometheus.HistogramVec
+	transactions        *prometheus.CounterVec
+
+	openConnections   *prometheus.Desc
+	inUse             *prometheus.Desc
+	idle              *prometheus.Desc
+	maxOpen           *prometheus.Desc
+	waitCount         *prometheus.Desc
+	waitDuration      *prometheus.Desc
+	maxIdleClosed     *prometheus.Desc
+	maxIdleTimeClosed *prometheus.Desc
+	maxLifetimeClosed *prometheus.Desc
+}
+
+type options struct {
+	namespace   string
+	constLabels prometheus.Labels
+	buckets     []float64
+}
+
+// Option collector option
+type Option func(*options)
+
+// WithNamespace set metric namespace, default is dbo
+func WithNamespace(namespace string) Option {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// WithConstLabels set labels of all metrics, such as the database name when there are multiple dbo
+func WithConstLabels(labels prometheus.Labels) Option {
+	return func(o *options) {
+		o.constLabels = labels
+	}
+}
+
+// WithBuckets set buckets of latency histograms in seconds
+func WithBuckets(buckets []float64) Option {
+	return func(o *options) {
+		o.buckets = buckets
+	}
+}
+
+// New create collector and register it as observer of d. the collector must be registered to
+// a prometheus registry to be exposed, such as prometheus.MustRegister(metrics.New(d))
+func New(d *dbo.DBO, opts ...Option) *Collector {
+	c := newCollector(d.Stats, opts...)
+	d.AddObserver(c)
+
+	return c
+}
+
+func newCollector(stats func() sql.DBStats, opts ...Option) *Collector {
+	o := &options{
+		namespace: "dbo",
+		buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	poolDesc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(o.namespace, "pool", name), help, nil, o.constLabels)
+	}
+
+	return &Collector{
+		stats: stats,
+		operationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   o.namespace,
+			Name:        "operation_duration_seconds",
+			Help:        "Duration of dbo operations.",
+			ConstLabels: o.constLabels,
+			Buckets:     o.buckets,
+		}, []string{"operation", "table"}),
+		operationErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   o.namespace,
+			Name:        "operation_errors_total",
+			Help:        "Failed dbo operations by classified error type.",
+			ConstLabels: o.constLabels,
+		}, []string{"operation", "table", "error"}),
+		transactionDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   o.namespace,
+			Name:        "transaction_duration_seconds",
+			Help:        "Duration of transactions from begin to commit or rollback.",
+			ConstLabels: o.constLabels,
+			Buckets:     o.buckets,
+		}, []string{"result"}),
+		transactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   o.namespace,
+			Name:        "transactions_total",
+			Help:        "Transactions by result (commit, rollback, timeout or error of commit) and classified error type.",
+			ConstLabels: o.constLabels,
+		}, []string{"result", "error"}),
+		openConnections:   poolDesc("open_connections", "Established connections both in use and idle."),
+		inUse:             poolDesc("in_use_connections", "Connections currently in use."),
+		idle:              poolDesc("idle_connections", "Idle connections."),
+		maxOpen:           poolDesc("max_open_connections", "Maximum number of open connections."),
+		waitCount:         poolDesc("wait_count_total", "Total number of connections waited for."),
+		waitDuration:      poolDesc("wait_duration_seconds_total", "Total time blocked waiting for a new connection."),
+		maxIdleClosed:     poolDesc("max_idle_closed_total", "Connections closed due to max idle connections."),
+		maxIdleTimeClosed: poolDesc("max_idle_time_closed_total", "Connections closed due to max idle time."),
+		maxLifetimeClosed: poolDesc("max_lifetime_closed_total", "Connections closed due to max connection lifetime."),
+	}
+}
+
+// ObserveOperation record latency and error of operation
+func (c *Collector) ObserveOperation(ctx context.Context, event dbo.OperationEvent) {
+	c.operationDuration.WithLabelValues(event.Op, event.Table).Observe(event.Duration.Seconds())
+	if event.Err != nil {
+		c.operationErrors.WithLabelValues(event.Op, event.Table, dbo.ErrorType(event.Err)).Inc()
+	}
+}
+
+// ObserveTransaction record latency and result of transaction
+func (c *Collector) ObserveTransaction(ctx context.Context, event dbo.TransactionEvent) {
+	c.transactionDuration.WithLabelValues(event.Result).Observe(event.Duration.Seconds())
+	c.transactions.WithLabelValues(event.Result, dbo.ErrorType(event.Err)).Inc()
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.operationDuration.Describe(ch)
+	c.operationErrors.Describe(ch)
+	c.transactionDuration.Describe(ch)
+	c.transactions.Describe(ch)
+
+	ch <- c.openConnections
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.maxOpen
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.maxIdleClosed
+	ch <- c.maxIdleTimeClosed
+	ch <- c.maxLifetimeClosed
+}
+
+// Collect implements prometheus.Collector, pool metrics are read from sql.DBStats on every scrape
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.operationDuration.Collect(ch)
+	c.operationErrors.Collect(ch)
+	c.transactionDuration.Collect(ch)
+	c.transactions.Collect(ch)
+
+	stats := c.stats()
+	ch <- prometheus.MustNewConstMetric(c.openConnections, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(c.maxOpen, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.maxIdleClosed, prometheus.CounterValue, float64(stats.MaxIdleClosed))
+	ch <- prometheus.MustNewConstMetric(c.maxIdleTimeClosed, prometheus.CounterValue, float64(stats.MaxIdleTimeClosed))
+	ch <- prometheus.MustNewConstMetric(c.maxLifetimeClosed, prometheus.CounterValue, float64(stats.MaxLifetimeClosed))
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nzai/dbo/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	c := newCollector(func() sql.DBStats {
+		return sql.DBStats{OpenConnections: 3, InUse: 2, Idle: 1, MaxOpenConnections: 10}
+	})
+
+	ctx := context.Background()
+	c.ObserveOperation(ctx, dbo.OperationEvent{Op: dbo.OpGet, Table: "table_a", Duration: time.Millisecond, Rows: 1})
+	c.ObserveOperation(ctx, dbo.OperationEvent{Op: dbo.OpGet, Table: "table_a", Duration: time.Millisecond, Err: dbo.ErrRecordNotFound})
+	c.ObserveTransaction(ctx, dbo.TransactionEvent{Result: dbo.TransactionTimeout, Duration: time.Second, Err: context.DeadlineExceeded})
+
+	if got := testutil.ToFloat64(c.operationErrors.WithLabelValues(dbo.OpGet, "table_a", "not_found")); got != 1 {
+		t.Errorf("operation errors = %v, want 1", got)
+	}
+
+	if got := testutil.ToFloat64(c.transactions.WithLabelValues(dbo.TransactionTimeout, "timeout")); got != 1 {
+		t.Errorf("transactions = %v, want 1", got)
+	}
+
+	expected := `
+# HELP dbo_pool_in_use_connections Connections currently in use.
+# TYPE dbo_pool_in_use_connections gauge
+dbo_pool_in_use_connections 2
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(expected), "dbo_pool_in_use_connections")
+	if err != nil {
+		t.Error(err)
+	}
+}
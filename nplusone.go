@@ -0,0 +1,118 @@
+package dbo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/nzai/log"
+	"github.com/pingcap/tidb/pkg/parser"
+	"gorm.io/gorm"
+)
+
+type queryTrackerKey struct{}
+
+// queryTracker count statements by fingerprint within a context
+type queryTracker struct {
+	mutex  sync.Mutex
+	counts map[string]int
+}
+
+// TrackQueries track select statements executed with ctx for N+1 query detection, such as the context of a request.
+// transactions are tracked automatically when detection is enabled, writes such as InsertTx in a loop are not counted
+func TrackQueries(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(queryTrackerKey{}).(*queryTracker); ok {
+		return ctx
+	}
+
+	return context.WithValue(ctx, queryTrackerKey{}, &queryTracker{counts: make(map[string]int)})
+}
+
+// nPlusOneDetector detect statements with the same fingerprint executed too many times within a tracked context
+type nPlusOneDetector struct {
+	threshold int
+	fail      bool
+}
+
+// newNPlusOneDetector create detector, nil if threshold is not positive
+func newNPlusOneDetector(config *Config) *nPlusOneDetector {
+	if config.NPlusOneThreshold <= 0 {
+		return nil
+	}
+
+	return &nPlusOneDetector{threshold: config.NPlusOneThreshold, fail: config.NPlusOneFail}
+}
+
+// check count statement after it is executed, warn once when the count exceeds threshold
+func (d *nPlusOneDetector) check(db *gorm.DB) {
+	if d == nil || db.DryRun || db.Statement.SQL.Len() == 0 {
+		return
+	}
+
+	fingerprint, count, ok := d.count(db.Statement.Context, db.Statement.SQL.String())
+	if ok && count == d.threshold+1 {
+		log.Warn(db.Statement.Context, "possible N+1 query, use batch queries such as GetMany or Query with IN condition",
+			log.String("fingerprint", fingerprint),
+			log.Int("threshold", d.threshold),
+			log.String("caller", caller()))
+	}
+}
+
+// reject count statement before it is executed in fail mode, every statement exceeding threshold fails without
+// being executed. sql of statements built by gorm later is built by build in a dry run session
+func (d *nPlusOneDetector) reject(build func(*gorm.DB)) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Error != nil || db.DryRun {
+			return
+		}
+
+		if _, ok := db.Statement.Context.Value(queryTrackerKey{}).(*queryTracker); !ok {
+			return
+		}
+
+		sql := db.Statement.SQL.String()
+		if sql == "" && build != nil {
+			tx := db.Session(&gorm.Session{DryRun: true})
+			build(tx)
+			if tx.Error != nil {
+				return
+			}
+			sql = tx.Statement.SQL.String()
+		}
+
+		fingerprint, count, ok := d.count(db.Statement.Context, sql)
+		if ok {
+			db.AddError(fmt.Errorf("%w: %s executed %d times, use batch queries such as GetMany or Query with IN condition",
+				ErrNPlusOneQuery, fingerprint, count))
+		}
+	}
+}
+
+// count count select sql in the tracker of ctx, ok is true if the count exceeds threshold
+func (d *nPlusOneDetector) count(ctx context.Context, sql string) (string, int, bool) {
+	tracker, ok := ctx.Value(queryTrackerKey{}).(*queryTracker)
+	if !ok || !isSelect(sql) {
+		return "", 0, false
+	}
+
+	fingerprint, digest := parser.NormalizeDigest(sql)
+	tracker.mutex.Lock()
+	tracker.counts[digest.String()]++
+	count := tracker.counts[digest.String()]
+	tracker.mutex.Unlock()
+
+	return fingerprint, count, count > d.threshold
+}
+
+// isSelect check whether sql is a select statement, including common table expressions
+func isSelect(sql string) bool {
+	sql = strings.TrimLeft(sql, " \t\r\n(")
+	for _, keyword := range []string{"SELECT", "WITH"} {
+		if len(sql) > len(keyword) && strings.EqualFold(sql[:len(keyword)], keyword) && !isNamePart(sql[len(keyword)]) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,90 @@
+package dbo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestNPlusOneDetector(t *testing.T) {
+	d := &nPlusOneDetector{threshold: 2, fail: true}
+	ctx := TrackQueries(context.Background())
+
+	for id := 1; id <= 3; id++ {
+		db := newStatement(fmt.Sprintf("SELECT * FROM `table_a` WHERE `id` = %d", id), 0, 1, nil)
+		db.Statement.Context = ctx
+		d.reject(nil)(db)
+
+		if id <= 2 && db.Error != nil {
+			t.Errorf("reject() statement %d error = %v, want nil", id, db.Error)
+		}
+
+		if id == 3 && !errors.Is(db.Error, ErrNPlusOneQuery) {
+			t.Errorf("reject() statement %d error = %v, want %v", id, db.Error, ErrNPlusOneQuery)
+		}
+	}
+
+	// writes are not N+1 queries
+	for id := 1; id <= 3; id++ {
+		db := newStatement(fmt.Sprintf("UPDATE `table_a` SET `name` = 'a' WHERE `id` = %d", id), 0, 1, nil)
+		db.Statement.Context = ctx
+		d.reject(nil)(db)
+		if db.Error != nil {
+			t.Errorf("reject() update %d error = %v, want nil", id, db.Error)
+		}
+	}
+
+	// statements of untracked contexts are ignored
+	for id := 1; id <= 3; id++ {
+		db := newStatement("SELECT * FROM `table_a` WHERE `id` = 1", 0, 1, nil)
+		db.Statement.Context = context.Background()
+		d.reject(nil)(db)
+		if db.Error != nil {
+			t.Errorf("reject() untracked error = %v, want nil", db.Error)
+		}
+	}
+}
+
+func TestNPlusOneRejectBeforeExecuting(t *testing.T) {
+	config := getDefaultConfig()
+	// nothing listens on the port, executed statements fail with connection errors
+	config.ConnectionString = "root:123456@tcp(127.0.0.1:1)/testdb?timeout=1s"
+	config.NPlusOneThreshold = 1
+	config.NPlusOneFail = true
+
+	ctx := TrackQueries(context.Background())
+	session := newTestDBO(t, config).db.Session(&gorm.Session{Context: ctx, SkipDefaultTransaction: true})
+	for id := 1; id <= 2; id++ {
+		var value tableA
+		err := session.Where("id = ?", id).Find(&value).Error
+		if id == 1 && (err == nil || !strings.Contains(err.Error(), "dial")) {
+			t.Errorf("first query error = %v, want connection error", err)
+		}
+
+		// the statement is rejected without connecting
+		if id == 2 && (!errors.Is(err, ErrNPlusOneQuery) || strings.Contains(err.Error(), "dial")) {
+			t.Errorf("second query error = %v, want %v", err, ErrNPlusOneQuery)
+		}
+	}
+}
+
+func TestIsSelect(t *testing.T) {
+	tests := map[string]bool{
+		"SELECT * FROM `table_a`":                        true,
+		" (select 1) union (select 2)":                   true,
+		"WITH t AS (SELECT 1) SELECT * FROM t":           true,
+		"INSERT INTO `table_a` (`name`) VALUES ('a')":    false,
+		"UPDATE `table_a` SET `name` = 'a' WHERE id = 1": false,
+		"selection": false,
+	}
+
+	for sql, want := range tests {
+		if got := isSelect(sql); got != want {
+			t.Errorf("isSelect(%q) = %v, want %v", sql, got, want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package dbo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AddObserver register observer of operations and transactions
+func (s *DBO) AddObserver(observer Observer) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var observers []Observer
+	if current := s.observers.Load(); current != nil {
+		observers = append(observers, *current...)
+	}
+	observers = append(observers, observer)
+	s.observers.Store(&observers)
+}
+
+func (s *DBO) observeOperation(ctx context.Context, event OperationEvent) {
+	observers := s.observers.Load()
+	if observers == nil {
+		return
+	}
+
+	for _, observer := range *observers {
+		observer.ObserveOperation(ctx, event)
+	}
+}
+
+// finishTransaction end transaction span and notify observers, timeout if ctx deadline exceeded before commit,
+// error if commit failed
+func (s *DBO) finishTransaction(ctx context.Context, span trace.Span, start time.Time, committed bool, err error) {
+	event := TransactionEvent{
+		Result:   TransactionCommit,
+		Duration: time.Since(start),
+		Err:      err,
+	}
+	if committed && err != nil {
+		event.Result = TransactionError
+	}
+	if !committed {
+		event.Result = TransactionRollback
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			event.Result = TransactionTimeout
+		}
+	}
+
+	endSpan(span, err, attribute.String("dbo.transaction.result", event.Result))
+
+	observers := s.observers.Load()
+	if observers == nil {
+		return
+	}
+
+	for _, observer := range *observers {
+		observer.ObserveTransaction(ctx, event)
+	}
+}
@@ -0,0 +1,132 @@
+package dbo
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// operation names of dbo helpers
+const (
+	OpInsert        = "insert"
+	OpInsertBatches = "insertBatches"
+	OpUpdate        = "update"
+	OpSave          = "save"
+	OpDelete        = "delete"
+	OpGet           = "get"
+	OpGetMany       = "getMany"
+	OpQuery         = "query"
+	OpCount         = "count"
+	OpExists        = "exists"
+	OpFirst         = "first"
+	OpPluck         = "pluck"
+	OpSum           = "sum"
+	OpAvg           = "avg"
+	OpMax           = "max"
+	OpMin           = "min"
+	OpGroupBy       = "groupBy"
+	OpRaw           = "raw"
+	OpExec          = "exec"
+	OpCommit        = "commit"
+)
+
+// transaction results
+const (
+	TransactionCommit   = "commit"
+	TransactionRollback = "rollback"
+	TransactionTimeout  = "timeout"
+	// TransactionError commit failed, the error type of the event classifies the failure
+	TransactionError = "error"
+)
+
+// OperationEvent finished operation of dbo helpers
+type OperationEvent struct {
+	// Op operation name, such as insert or query
+	Op string
+	// Table table name, empty for raw sql
+	Table string
+	// Duration duration of the operation
+	Duration time.Duration
+	// Rows rows affected or returned
+	Rows int64
+	// Err classified error, nil if the operation succeeded
+	Err error
+}
+
+// TransactionEvent finished transaction
+type TransactionEvent struct {
+	// Result commit, rollback, timeout or error if commit failed
+	Result string
+	// Duration duration from begin to commit or rollback
+	Duration time.Duration
+	// Err error of the transaction func, or commit error
+	Err error
+}
+
+// Observer observe operations and transactions, such as metrics collectors.
+// observers are called synchronously and should return quickly
+type Observer interface {
+	ObserveOperation(ctx context.Context, event OperationEvent)
+	ObserveTransaction(ctx context.Context, event TransactionEvent)
+}
+
+// operation operation of dbo helpers in progress
+type operation struct {
+	ctx   context.Context
+	db    *DBContext
+	name  string
+	table string
+	start time.Time
+
+	// span recording span of the operation, parent statement context is restored when finished
+	span   trace.Span
+	parent context.Context
+}
+
+// beginOperation start timing operation of table
+func (s *DBContext) beginOperation(ctx context.Context, name, table string) *operation {
+	o := &operation{
+		ctx:   ctx,
+		db:    s,
+		name:  name,
+		table: table,
+		start: time.Now(),
+	}
+	if s.dbo == nil {
+		return o
+	}
+
+	spanCtx, span := s.dbo.startSpan(ctx, "dbo."+name, semconv.DBOperation(name), semconv.DBSQLTable(table))
+	if span.IsRecording() {
+		// statements of the operation are traced as children of the span
+		o.span = span
+		o.parent = s.Statement.Context
+		s.DB = s.DB.WithContext(spanCtx)
+	}
+
+	return o
+}
+
+// finish classify err and notify observers, return the classified error
+func (o *operation) finish(rows int64, err error) error {
+	err = newError(o.name, o.table, o.start, err)
+	if o.db.dbo != nil {
+		o.db.dbo.observeOperation(o.ctx, OperationEvent{
+			Op:       o.name,
+			Table:    o.table,
+			Duration: time.Since(o.start),
+			Rows:     rows,
+			Err:      err,
+		})
+	}
+
+	if o.span != nil {
+		o.db.DB = o.db.DB.WithContext(o.parent)
+		endSpan(o.span, err, attribute.Int64("dbo.rows", rows))
+	}
+
+	return err
+}
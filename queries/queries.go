@@ -0,0 +1,289 @@
+package queries
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nzai/dbo/v2"
+	"github.com/pingcap/tidb/pkg/parser"
+	_ "github.com/pingcap/tidb/pkg/parser/test_driver"
+)
+
+var (
+	// ErrQueryNotFound named query not found
+	ErrQueryNotFound = errors.New("named query not found")
+
+	globalRegistry = &Registry{queries: make(map[string]*Query)}
+	globalMutex    sync.RWMutex
+
+	nameRegex    = regexp.MustCompile(`^--\s*name:\s*(\S+)\s*$`)
+	inParamRegex = regexp.MustCompile(`(?i)\bin\s*\?`)
+)
+
+// Query named sql query
+type Query struct {
+	Name       string
+	File       string
+	SQL        string
+	Parameters []string
+}
+
+// Registry named queries registry
+type Registry struct {
+	queries map[string]*Query
+}
+
+// Load load named queries from .sql files of fsys matched by patterns, all .sql files are loaded if no pattern given.
+// every query starts with a "-- name: QueryName" line and is validated by the tidb parser
+func Load(fsys fs.FS, patterns ...string) (*Registry, error) {
+	files, err := matchFiles(fsys, patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := &Registry{queries: make(map[string]*Query)}
+	for _, file := range files {
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		queries, err := parseFile(file, string(content))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, query := range queries {
+			exists, found := registry.queries[query.Name]
+			if found {
+				return nil, fmt.Errorf("duplicate query %s in %s and %s", query.Name, exists.File, query.File)
+			}
+
+			err = validate(query)
+			if err != nil {
+				return nil, err
+			}
+
+			registry.queries[query.Name] = query
+		}
+	}
+
+	return registry, nil
+}
+
+// MustLoad load named queries otherwise panic
+func MustLoad(fsys fs.FS, patterns ...string) *Registry {
+	registry, err := Load(fsys, patterns...)
+	if err != nil {
+		panic(err)
+	}
+
+	return registry
+}
+
+// Get get named query
+func (r *Registry) Get(name string) (*Query, error) {
+	query, found := r.queries[name]
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrQueryNotFound, name)
+	}
+
+	return query, nil
+}
+
+// Names get sorted names of all queries
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.queries))
+	for name := range r.queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ReplaceGlobal replace global registry used by RunNamed
+func ReplaceGlobal(registry *Registry) {
+	globalMutex.Lock()
+	defer globalMutex.Unlock()
+
+	globalRegistry = registry
+}
+
+// GetGlobal get global registry
+func GetGlobal() *Registry {
+	globalMutex.RLock()
+	defer globalMutex.RUnlock()
+
+	return globalRegistry
+}
+
+// RunNamed run named query of global registry, params is a map[string]any or struct for ":name" parameters
+func RunNamed[T any](ctx context.Context, name string, params any) ([]T, error) {
+	db, err := dbo.GetDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return RunNamedTx[T](ctx, db, name, params)
+}
+
+// RunNamedTx run named query of global registry with db context
+func RunNamedTx[T any](ctx context.Context, db *dbo.DBContext, name string, params any) ([]T, error) {
+	query, err := GetGlobal().Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	arguments, err := args(query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return dbo.RawTx[T](ctx, db, query.SQL, arguments...)
+}
+
+// ExecNamed execute named statement of global registry
+func ExecNamed(ctx context.Context, name string, params any) (int64, error) {
+	db, err := dbo.GetDB(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return ExecNamedTx(ctx, db, name, params)
+}
+
+// ExecNamedTx execute named statement of global registry with db context
+func ExecNamedTx(ctx context.Context, db *dbo.DBContext, name string, params any) (int64, error) {
+	query, err := GetGlobal().Get(name)
+	if err != nil {
+		return 0, err
+	}
+
+	arguments, err := args(query, params)
+	if err != nil {
+		return 0, err
+	}
+
+	return dbo.ExecTx(ctx, db, query.SQL, arguments...)
+}
+
+// args get arguments of query, params is required by queries with ":name" parameters
+func args(query *Query, params any) ([]any, error) {
+	if params != nil {
+		return []any{params}, nil
+	}
+
+	if len(query.Parameters) > 0 {
+		return nil, fmt.Errorf("%w: query %s requires parameters %v", dbo.ErrInvalidParameter, query.Name, query.Parameters)
+	}
+
+	return nil, nil
+}
+
+// matchFiles get sorted file names of fsys matched by patterns
+func matchFiles(fsys fs.FS, patterns []string) ([]string, error) {
+	files := make([]string, 0)
+	if len(patterns) == 0 {
+		err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !d.IsDir() && strings.HasSuffix(path, ".sql") {
+				files = append(files, path)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return files, nil
+	}
+
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// parseFile split sql file into named queries
+func parseFile(file, content string) ([]*Query, error) {
+	queries := make([]*Query, 0)
+	var current *Query
+	var builder strings.Builder
+	flush := func() {
+		if current == nil {
+			return
+		}
+
+		current.SQL = strings.TrimSuffix(strings.TrimSpace(builder.String()), ";")
+		queries = append(queries, current)
+		builder.Reset()
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+
+		matches := nameRegex.FindStringSubmatch(strings.TrimSpace(line))
+		if len(matches) > 1 {
+			flush()
+			current = &Query{Name: matches[1], File: file}
+			continue
+		}
+
+		if current == nil {
+			if strings.TrimSpace(line) != "" && !strings.HasPrefix(strings.TrimSpace(line), "--") {
+				return nil, fmt.Errorf("%s:%d: sql before the first \"-- name:\" annotation", file, lineNum)
+			}
+			continue
+		}
+
+		builder.WriteString(line)
+		builder.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+
+	return queries, nil
+}
+
+// validate check query syntax by tidb parser
+func validate(query *Query) error {
+	if query.SQL == "" {
+		return fmt.Errorf("%s: query %s is empty", query.File, query.Name)
+	}
+
+	sql, parameters := dbo.ParseNamed(query.SQL)
+	query.Parameters = parameters
+
+	// slice parameters are expanded to (?, ?) when executing
+	sql = inParamRegex.ReplaceAllString(sql, "in (?)")
+	_, _, err := parser.New().Parse(sql, "", "")
+	if err != nil {
+		return fmt.Errorf("%s: invalid query %s: %w", query.File, query.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package queries
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nzai/dbo/v2"
+)
+
+func TestLoad(t *testing.T) {
+	registry, err := Load(fstest.MapFS{
+		"table_a.sql": &fstest.MapFile{Data: []byte(`-- name: GetTableAByName
+select * from table_a
+where name = :name;
+
+-- name: GetTableAByIDs
+select * from table_a where id in :ids;
+`)},
+	})
+	if err != nil {
+		t.Fatalf("failed to load queries due to %v", err)
+	}
+
+	if !reflect.DeepEqual(registry.Names(), []string{"GetTableAByIDs", "GetTableAByName"}) {
+		t.Errorf("Names() = %v", registry.Names())
+	}
+
+	query, err := registry.Get("GetTableAByName")
+	if err != nil {
+		t.Fatalf("failed to get query due to %v", err)
+	}
+
+	if query.SQL != "select * from table_a\nwhere name = :name" {
+		t.Errorf("SQL = %q", query.SQL)
+	}
+
+	if !reflect.DeepEqual(query.Parameters, []string{"name"}) {
+		t.Errorf("Parameters = %v", query.Parameters)
+	}
+
+	_, err = args(query, nil)
+	if !errors.Is(err, dbo.ErrInvalidParameter) {
+		t.Errorf("args() of nil params error = %v, want %v", err, dbo.ErrInvalidParameter)
+	}
+
+	arguments, err := args(&Query{Name: "CountTableA", SQL: "select count(*) from table_a"}, nil)
+	if err != nil || arguments != nil {
+		t.Errorf("args() of query without parameters = %v, %v", arguments, err)
+	}
+
+	_, err = Load(fstest.MapFS{
+		"bad.sql": &fstest.MapFile{Data: []byte("-- name: Bad\nselec * from table_a")},
+	})
+	if err == nil {
+		t.Errorf("Load() should fail for invalid sql")
+	}
+}
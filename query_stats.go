@@ -0,0 +1,239 @@
+package dbo
+
+import (
+	"cmp"
+	"container/list"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nzai/log"
+	"github.com/pingcap/tidb/pkg/parser"
+	"gorm.io/gorm"
+)
+
+const (
+	// queryStatSamples latency samples kept by every fingerprint to estimate percentiles
+	queryStatSamples = 128
+	// queryStatMinAge fingerprints recorded within min age are never evicted, so that new fingerprints
+	// can not evict each other before they get enough samples
+	queryStatMinAge = time.Minute
+)
+
+// QueryStat statistics of statements with the same normalized sql
+type QueryStat struct {
+	// Digest hash of Fingerprint
+	Digest string `json:"digest"`
+	// Fingerprint normalized sql, literals are replaced with "?"
+	Fingerprint  string        `json:"fingerprint"`
+	Count        int64         `json:"count"`
+	Errors       int64         `json:"errors"`
+	Rows         int64         `json:"rows"`
+	TotalLatency time.Duration `json:"-"`
+	P50          time.Duration `json:"-"`
+	P99          time.Duration `json:"-"`
+	MaxLatency   time.Duration `json:"-"`
+}
+
+// MarshalJSON marshal latencies as readable durations
+func (s QueryStat) MarshalJSON() ([]byte, error) {
+	type stat QueryStat
+	return json.Marshal(struct {
+		stat
+		TotalLatency string `json:"totalLatency"`
+		P50          string `json:"p50"`
+		P99          string `json:"p99"`
+		MaxLatency   string `json:"maxLatency"`
+	}{stat(s), s.TotalLatency.String(), s.P50.String(), s.P99.String(), s.MaxLatency.String()})
+}
+
+// queryStat statistics of a fingerprint with recent latency samples
+type queryStat struct {
+	QueryStat
+	samples []time.Duration
+	next    int
+	created time.Time
+	element *list.Element
+}
+
+// queryStats statistics by fingerprint, at most limit fingerprints are kept
+type queryStats struct {
+	limit  int
+	minAge time.Duration
+	mutex  sync.Mutex
+	stats  map[string]*queryStat
+	// lru keys of fingerprints, most recently recorded first
+	lru *list.List
+}
+
+// newQueryStats create query stats, nil if limit is not positive
+func newQueryStats(limit int) *queryStats {
+	if limit <= 0 {
+		return nil
+	}
+
+	return &queryStats{limit: limit, minAge: queryStatMinAge, stats: make(map[string]*queryStat), lru: list.New()}
+}
+
+// record add statement to the stats of its fingerprint
+func (s *queryStats) record(db *gorm.DB) {
+	if s == nil || db.DryRun || db.Statement.SQL.Len() == 0 {
+		return
+	}
+
+	duration := statementDuration(db)
+	failed := db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound)
+	fingerprint, digest := parser.NormalizeDigest(db.Statement.SQL.String())
+	key := digest.String()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stat, ok := s.stats[key]
+	if ok {
+		s.lru.MoveToFront(stat.element)
+	} else {
+		// new fingerprints are dropped if every fingerprint is too young to evict
+		if len(s.stats) >= s.limit && !s.evict() {
+			return
+		}
+
+		stat = &queryStat{
+			QueryStat: QueryStat{Digest: key, Fingerprint: fingerprint},
+			samples:   make([]time.Duration, 0, queryStatSamples),
+			created:   time.Now(),
+			element:   s.lru.PushFront(key),
+		}
+		s.stats[key] = stat
+	}
+
+	stat.Count++
+	stat.Rows += db.RowsAffected
+	stat.TotalLatency += duration
+	stat.MaxLatency = max(stat.MaxLatency, duration)
+	if failed {
+		stat.Errors++
+	}
+
+	if len(stat.samples) < queryStatSamples {
+		stat.samples = append(stat.samples, duration)
+	} else {
+		stat.samples[stat.next] = duration
+		stat.next = (stat.next + 1) % queryStatSamples
+	}
+}
+
+// evict remove the least recently recorded fingerprint unless it is younger than min age, false if nothing is evicted.
+// if it is younger than min age, every fingerprint was recorded within min age
+func (s *queryStats) evict() bool {
+	element := s.lru.Back()
+	if element == nil {
+		return false
+	}
+
+	key := element.Value.(string)
+	if time.Since(s.stats[key].created) < s.minAge {
+		return false
+	}
+
+	s.lru.Remove(element)
+	delete(s.stats, key)
+	return true
+}
+
+// snapshot get stats ordered by total latency desc, percentiles are estimated by recent samples
+func (s *queryStats) snapshot() []QueryStat {
+	if s == nil {
+		return []QueryStat{}
+	}
+
+	s.mutex.Lock()
+	stats := make([]QueryStat, 0, len(s.stats))
+	samples := make([][]time.Duration, 0, len(s.stats))
+	for _, stat := range s.stats {
+		stats = append(stats, stat.QueryStat)
+		samples = append(samples, slices.Clone(stat.samples))
+	}
+	s.mutex.Unlock()
+
+	for index := range stats {
+		slices.Sort(samples[index])
+		stats[index].P50 = percentile(samples[index], 0.5)
+		stats[index].P99 = percentile(samples[index], 0.99)
+	}
+
+	slices.SortFunc(stats, func(a, b QueryStat) int {
+		return cmp.Compare(b.TotalLatency, a.TotalLatency)
+	})
+
+	return stats
+}
+
+func (s *queryStats) reset() {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.stats = make(map[string]*queryStat)
+	s.lru.Init()
+}
+
+// percentile get p percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	index := int(float64(len(sorted))*p+0.5) - 1
+	return sorted[min(max(index, 0), len(sorted)-1)]
+}
+
+// QueryStats get statistics by normalized sql ordered by total latency desc, empty if query stats is disabled
+func (s *DBO) QueryStats() []QueryStat {
+	return s.queryStats.snapshot()
+}
+
+// ResetQueryStats clear query statistics
+func (s *DBO) ResetQueryStats() {
+	s.queryStats.reset()
+}
+
+// QueryStatsHandler http handler of query statistics. GET responds the stats as json,
+// limited by the optional "limit" query parameter. DELETE resets the stats
+func (s *DBO) QueryStatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodDelete:
+			s.ResetQueryStats()
+			w.WriteHeader(http.StatusNoContent)
+			return
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		stats := s.QueryStats()
+		if value := r.URL.Query().Get("limit"); value != "" {
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit < 0 {
+				http.Error(w, "invalid limit", http.StatusBadRequest)
+				return
+			}
+			stats = stats[:min(limit, len(stats))]
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(stats)
+		if err != nil {
+			log.Warn(r.Context(), "write query stats failed", log.Err(err))
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package dbo
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func newStatement(sql string, duration time.Duration, rows int64, err error) *gorm.DB {
+	db := &gorm.DB{Config: &gorm.Config{}, Statement: &gorm.Statement{}, RowsAffected: rows, Error: err}
+	db.Statement.SQL.WriteString(sql)
+	db.InstanceSet(statementStartKey, time.Now().Add(-duration))
+	return db
+}
+
+func TestQueryStats(t *testing.T) {
+	s := newQueryStats(2)
+	s.record(newStatement("SELECT * FROM `table_a` WHERE `id` = 1", 10*time.Millisecond, 1, nil))
+	s.record(newStatement("SELECT * FROM `table_a` WHERE `id` = 2", 30*time.Millisecond, 0, gorm.ErrRecordNotFound))
+	s.record(newStatement("SELECT * FROM `table_a` WHERE `id` IN (1,2,3)", 100*time.Millisecond, 3, errors.New("failed")))
+
+	stats := s.snapshot()
+	if len(stats) != 2 {
+		t.Fatalf("snapshot() = %d stats, want 2", len(stats))
+	}
+
+	if stats[0].Count != 1 || stats[0].Errors != 1 || stats[0].Rows != 3 {
+		t.Errorf("stats[0] = %+v", stats[0])
+	}
+
+	if stats[1].Count != 2 || stats[1].Errors != 0 || stats[1].Rows != 1 {
+		t.Errorf("stats[1] = %+v", stats[1])
+	}
+
+	if stats[1].P50 < 10*time.Millisecond || stats[1].P99 < 30*time.Millisecond {
+		t.Errorf("stats[1] percentiles = %s, %s", stats[1].P50, stats[1].P99)
+	}
+
+	// young fingerprints are not evicted, the new one is dropped
+	s.record(newStatement("SELECT * FROM `table_b`", 200*time.Millisecond, 0, nil))
+	stats = s.snapshot()
+	if len(stats) != 2 || stats[0].TotalLatency == 200*time.Millisecond {
+		t.Errorf("snapshot() with young fingerprints = %+v", stats)
+	}
+
+	// the least recently recorded fingerprint is evicted when limit is reached
+	s.minAge = 0
+	s.record(newStatement("SELECT * FROM `table_b`", 200*time.Millisecond, 0, nil))
+	stats = s.snapshot()
+	if len(stats) != 2 || stats[0].Count != 1 || stats[1].Count != 1 || stats[1].Rows != 3 {
+		t.Errorf("snapshot() after eviction = %+v", stats)
+	}
+
+	s.reset()
+	if stats = s.snapshot(); len(stats) != 0 {
+		t.Errorf("snapshot() after reset = %+v", stats)
+	}
+}
@@ -0,0 +1,318 @@
+package dbo
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/nzai/log"
+	"gorm.io/gorm/schema"
+)
+
+// Raw query values of T with raw sql. args can be positional parameters for "?",
+// or a single map[string]any / struct wrapped by Named for ":name" parameters. a single map or struct
+// not wrapped is bound by name only if sql has ":name" parameters, such as a json value is positional
+func Raw[T any](ctx context.Context, sql string, args ...any) ([]T, error) {
+	db, err := GetDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return RawTx[T](ctx, db, sql, args...)
+}
+
+// RawTx query values of T with raw sql and db context
+func RawTx[T any](ctx context.Context, db *DBContext, sql string, args ...any) ([]T, error) {
+	query, parameters, err := bindArgs(sql, args)
+	if err != nil {
+		log.Warn(ctx, "raw query failed due to invalid parameters",
+			log.Err(err),
+			log.String("sql", sql),
+			log.Any("args", db.filterParams(sql, args)))
+		return nil, err
+	}
+
+	op := db.beginOperation(ctx, OpRaw, "")
+	values := make([]T, 0)
+	err = db.ResetCondition().Raw(query, parameters...).Scan(&values).Error
+	if err != nil {
+		err = op.finish(0, err)
+		log.Warn(ctx, "raw query failed",
+			log.Err(err),
+			log.String("sql", query),
+			log.Any("args", db.filterParams(query, parameters)),
+			log.Duration("duration", time.Since(op.start)))
+		return nil, err
+	}
+
+	op.finish(int64(len(values)), nil)
+	log.Debug(ctx, "raw query successfully",
+		log.String("sql", query),
+		log.Any("args", db.filterParams(query, parameters)),
+		log.Int("count", len(values)),
+		log.Duration("duration", time.Since(op.start)))
+
+	return values, nil
+}
+
+// Exec execute raw sql, return rows affected. args are the same as Raw
+func Exec(ctx context.Context, sql string, args ...any) (int64, error) {
+	db, err := GetDB(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return ExecTx(ctx, db, sql, args...)
+}
+
+// ExecTx execute raw sql with db context
+func ExecTx(ctx context.Context, db *DBContext, sql string, args ...any) (int64, error) {
+	query, parameters, err := bindArgs(sql, args)
+	if err != nil {
+		log.Warn(ctx, "exec failed due to invalid parameters",
+			log.Err(err),
+			log.String("sql", sql),
+			log.Any("args", db.filterParams(sql, args)))
+		return 0, err
+	}
+
+	op := db.beginOperation(ctx, OpExec, "")
+	newDB := db.ResetCondition().Exec(query, parameters...)
+	if newDB.Error != nil {
+		err = op.finish(0, newDB.Error)
+		log.Warn(ctx, "exec failed",
+			log.Err(err),
+			log.String("sql", query),
+			log.Any("args", db.filterParams(query, parameters)),
+			log.Duration("duration", time.Since(op.start)))
+		return 0, err
+	}
+
+	op.finish(newDB.RowsAffected, nil)
+	log.Debug(ctx, "exec successfully",
+		log.String("sql", query),
+		log.Any("args", db.filterParams(query, parameters)),
+		log.Int64("rowsAffected", newDB.RowsAffected),
+		log.Duration("duration", time.Since(op.start)))
+
+	return newDB.RowsAffected, nil
+}
+
+// NamedArg source of ":name" parameters of Raw and Exec, created by Named
+type NamedArg struct {
+	value any
+}
+
+// Named bind ":name" parameters of Raw and Exec with values of a map[string]any or struct
+func Named(arg any) NamedArg {
+	return NamedArg{value: arg}
+}
+
+// bindArgs bind named parameters if args is a single Named, or a single map or struct and sql has ":name"
+// parameters, otherwise args are positional
+func bindArgs(sql string, args []any) (string, []any, error) {
+	if len(args) != 1 {
+		return sql, args, nil
+	}
+
+	if named, ok := args[0].(NamedArg); ok {
+		return BindNamed(sql, named.value)
+	}
+
+	if !isNamedArg(args[0]) {
+		return sql, args, nil
+	}
+
+	if _, names := ParseNamed(sql); len(names) == 0 {
+		return sql, args, nil
+	}
+
+	return BindNamed(sql, args[0])
+}
+
+// isNamedArg check whether arg can provide named parameters
+func isNamedArg(arg any) bool {
+	if arg == nil {
+		return false
+	}
+
+	if _, ok := arg.(driver.Valuer); ok {
+		return false
+	}
+
+	if _, ok := arg.(time.Time); ok {
+		return false
+	}
+
+	rt := reflect.TypeOf(arg)
+	for rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+
+	switch rt.Kind() {
+	case reflect.Map:
+		return rt.Key().Kind() == reflect.String
+	case reflect.Struct:
+		return rt != reflect.TypeOf(time.Time{})
+	default:
+		return false
+	}
+}
+
+// ParseNamed replace ":name" parameters of sql with "?", return the parameter names in order.
+// parameters in quoted strings, quoted identifiers and comments are ignored
+func ParseNamed(sql string) (string, []string) {
+	var builder strings.Builder
+	builder.Grow(len(sql))
+	names := make([]string, 0)
+
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			// quoted string or identifier
+			end := i + 1
+			for end < len(sql) {
+				if sql[end] == '\\' && c != '`' {
+					end += 2
+					continue
+				}
+
+				if sql[end] == c {
+					break
+				}
+				end++
+			}
+			end = min(end, len(sql)-1)
+			builder.WriteString(sql[i : end+1])
+			i = end
+		case c == '-' && isDashComment(sql[i:]), c == '#':
+			// line comment
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i - 1
+			}
+			builder.WriteString(sql[i : i+end+1])
+			i += end
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			// block comment
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				end = len(sql) - i - 1
+			} else {
+				end += 3
+			}
+			builder.WriteString(sql[i : i+end+1])
+			i += end
+		case c == ':' && i+1 < len(sql) && isNameStart(sql[i+1]) && (i == 0 || sql[i-1] != ':'):
+			end := i + 1
+			for end < len(sql) && isNamePart(sql[end]) {
+				end++
+			}
+			names = append(names, sql[i+1:end])
+			builder.WriteByte('?')
+			i = end - 1
+		default:
+			builder.WriteByte(c)
+		}
+	}
+
+	return builder.String(), names
+}
+
+// BindNamed replace ":name" parameters of sql with "?" and pick their values from a map or struct.
+// struct fields are matched by column name, field name or snake case field name
+func BindNamed(sql string, arg any) (string, []any, error) {
+	query, names := ParseNamed(sql)
+	values, err := namedValues(arg)
+	if err != nil {
+		return "", nil, err
+	}
+
+	parameters := make([]any, len(names))
+	for index, name := range names {
+		value, ok := values(name)
+		if !ok {
+			return "", nil, fmt.Errorf("%w: missing value of :%s", ErrInvalidParameter, name)
+		}
+
+		parameters[index] = value
+	}
+
+	return query, parameters, nil
+}
+
+// namedValues get lookup function of named values
+func namedValues(arg any) (func(string) (any, bool), error) {
+	rv := reflect.ValueOf(arg)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("%w: nil named parameters", ErrInvalidParameter)
+		}
+		rv = rv.Elem()
+	}
+
+	switch {
+	case rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String:
+		return func(name string) (any, bool) {
+			value := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+			if !value.IsValid() {
+				return nil, false
+			}
+
+			return value.Interface(), true
+		}, nil
+	case rv.Kind() == reflect.Struct:
+		fields := make(map[string]any)
+		collectStructFields(rv, fields)
+		return func(name string) (any, bool) {
+			value, ok := fields[name]
+			return value, ok
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported named parameters type %T", ErrInvalidParameter, arg)
+	}
+}
+
+// collectStructFields collect exported field values of struct, embedded structs are flattened
+func collectStructFields(rv reflect.Value, fields map[string]any) {
+	namer := schema.NamingStrategy{}
+	rt := rv.Type()
+	for index := 0; index < rt.NumField(); index++ {
+		field := rt.Field(index)
+		if !field.IsExported() {
+			continue
+		}
+
+		value := rv.Field(index)
+		if field.Anonymous && value.Kind() == reflect.Struct {
+			collectStructFields(value, fields)
+			continue
+		}
+
+		fields[field.Name] = value.Interface()
+		fields[namer.ColumnName("", field.Name)] = value.Interface()
+
+		column, ok := schema.ParseTagSetting(field.Tag.Get("gorm"), ";")["COLUMN"]
+		if ok {
+			fields[column] = value.Interface()
+		}
+	}
+}
+
+// isDashComment check whether sql starts with "--" comment, which is followed by whitespace, control character
+// or the end of sql in mysql
+func isDashComment(sql string) bool {
+	return strings.HasPrefix(sql, "--") && (len(sql) == 2 || sql[2] <= ' ')
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNamePart(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
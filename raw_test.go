@@ -0,0 +1,134 @@
+package dbo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBindNamed(t *testing.T) {
+	type filter struct {
+		Name     string
+		MinScore int `gorm:"column:score"`
+	}
+
+	tests := []struct {
+		name           string
+		sql            string
+		arg            any
+		wantSQL        string
+		wantParameters []any
+		wantErr        bool
+	}{
+		{
+			name:           "map",
+			sql:            "select * from table_a where name=:name and id in :ids",
+			arg:            map[string]any{"name": "a", "ids": []int{1, 2}},
+			wantSQL:        "select * from table_a where name=? and id in ?",
+			wantParameters: []any{"a", []int{1, 2}},
+		},
+		{
+			name:           "struct",
+			sql:            "select * from table_a where name=:name and score>=:score and remark=:Name",
+			arg:            &filter{Name: "a", MinScore: 60},
+			wantSQL:        "select * from table_a where name=? and score>=? and remark=?",
+			wantParameters: []any{"a", 60, "a"},
+		},
+		{
+			name:           "ignore quotes and comments",
+			sql:            "select ':name', `a:b` from table_a -- :name\nwhere id=:id /* :id */",
+			arg:            map[string]any{"id": 9},
+			wantSQL:        "select ':name', `a:b` from table_a -- :name\nwhere id=? /* :id */",
+			wantParameters: []any{9},
+		},
+		{
+			name:           "comments without space",
+			sql:            "select * from table_a --\t:name\n# :name\nwhere id=:id --\n",
+			arg:            map[string]any{"id": 9},
+			wantSQL:        "select * from table_a --\t:name\n# :name\nwhere id=? --\n",
+			wantParameters: []any{9},
+		},
+		{
+			name:    "missing",
+			sql:     "select * from table_a where id=:id",
+			arg:     map[string]any{},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotSQL, gotParameters, err := BindNamed(tt.sql, tt.arg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BindNamed() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if gotSQL != tt.wantSQL {
+				t.Errorf("BindNamed() sql = %v, want %v", gotSQL, tt.wantSQL)
+			}
+
+			if !reflect.DeepEqual(gotParameters, tt.wantParameters) {
+				t.Errorf("BindNamed() parameters = %v, want %v", gotParameters, tt.wantParameters)
+			}
+		})
+	}
+}
+
+func TestBindArgs(t *testing.T) {
+	type document struct {
+		Title string
+	}
+
+	tests := []struct {
+		name           string
+		sql            string
+		args           []any
+		wantSQL        string
+		wantParameters []any
+	}{
+		{
+			name:           "positional",
+			sql:            "select * from table_a where id=? and name=?",
+			args:           []any{1, "a"},
+			wantSQL:        "select * from table_a where id=? and name=?",
+			wantParameters: []any{1, "a"},
+		},
+		{
+			name:           "single positional struct",
+			sql:            "update table_a set remark=? where id=1",
+			args:           []any{document{Title: "a"}},
+			wantSQL:        "update table_a set remark=? where id=1",
+			wantParameters: []any{document{Title: "a"}},
+		},
+		{
+			name:           "map with named parameters",
+			sql:            "select * from table_a where id=:id",
+			args:           []any{map[string]any{"id": 1}},
+			wantSQL:        "select * from table_a where id=?",
+			wantParameters: []any{1},
+		},
+		{
+			name:           "named",
+			sql:            "select * from table_a where remark=:title",
+			args:           []any{Named(document{Title: "a"})},
+			wantSQL:        "select * from table_a where remark=?",
+			wantParameters: []any{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotSQL, gotParameters, err := bindArgs(tt.sql, tt.args)
+			if err != nil {
+				t.Fatalf("bindArgs() error = %v", err)
+			}
+
+			if gotSQL != tt.wantSQL || !reflect.DeepEqual(gotParameters, tt.wantParameters) {
+				t.Errorf("bindArgs() = %v %v, want %v %v", gotSQL, gotParameters, tt.wantSQL, tt.wantParameters)
+			}
+		})
+	}
+}
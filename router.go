@@ -0,0 +1,324 @@
+package dbo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/nzai/log"
+	"golang.org/x/sync/singleflight"
+)
+
+// minEvictInterval min interval of checking idle tenants
+const minEvictInterval = time.Second
+
+var globalRouter *TenantRouter
+
+// TenantRouter route tenants to their own database or schema, dbo of a tenant is created from the template config
+// on first use and closed after idle timeout
+type TenantRouter struct {
+	template    *Config
+	templateDSN string
+	resolver    TenantResolver
+	configure   func(tenant any) []Option
+	idleTimeout time.Duration
+
+	mutex   sync.Mutex
+	tenants map[string]*tenantDBO
+	closed  bool
+	group   singleflight.Group
+	stop    chan struct{}
+}
+
+type tenantDBO struct {
+	dbo *DBO
+	// lastUsed time of the last routing to dbo, guarded by mutex of router
+	lastUsed time.Time
+}
+
+// NewTenantRouter create tenant router. dbo of tenant is created with template config and options returned by
+// configure, such as WithDatabase or WithMaxOpenConns, so that every tenant has its own pool limits.
+// dbo of tenant unused for idleTimeout is closed, never closed if zero.
+// the database of a tenant is also applied to ConnectionString or MySQLConfig of template, tenants connecting to
+// the database of template or of another tenant fail
+func NewTenantRouter(template *Config, resolver TenantResolver, configure func(tenant any) []Option, idleTimeout time.Duration) (*TenantRouter, error) {
+	templateDSN, err := template.DSN()
+	if err != nil {
+		log.Warn(context.Background(), "get dsn of tenant template failed", log.Err(err))
+		return nil, err
+	}
+
+	router := &TenantRouter{
+		templateDSN: templateDSN,
+		template:    template,
+		resolver:    resolver,
+		configure:   configure,
+		idleTimeout: idleTimeout,
+		tenants:     make(map[string]*tenantDBO),
+		stop:        make(chan struct{}),
+	}
+
+	if idleTimeout > 0 {
+		go router.evictLoop()
+	}
+
+	return router, nil
+}
+
+// ReplaceGlobalRouter route db contexts and transactions of helpers by the tenant of context,
+// contexts without tenant or bypassed by WithoutTenant use the global dbo. routing is disabled if router is nil
+func ReplaceGlobalRouter(router *TenantRouter) {
+	globalMutex.Lock()
+	defer globalMutex.Unlock()
+
+	globalRouter = router
+}
+
+// getDBO get dbo of the tenant of ctx if the global router is set, otherwise the global dbo
+func getDBO(ctx context.Context) (*DBO, error) {
+	globalMutex.Lock()
+	router := globalRouter
+	globalMutex.Unlock()
+
+	if router == nil {
+		return GetGlobal()
+	}
+
+	dbo, ok, err := router.route(ctx)
+	if err != nil || ok {
+		return dbo, err
+	}
+
+	return GetGlobal()
+}
+
+// Get get dbo of the tenant of ctx, fails with ErrTenantRequired if ctx has no tenant
+func (r *TenantRouter) Get(ctx context.Context) (*DBO, error) {
+	dbo, ok, err := r.route(ctx)
+	if err == nil && !ok {
+		err = ErrTenantRequired
+	}
+
+	return dbo, err
+}
+
+// route get dbo of the tenant of ctx, ok is false if ctx has no tenant or is bypassed by WithoutTenant
+func (r *TenantRouter) route(ctx context.Context) (*DBO, bool, error) {
+	if bypass, _ := ctx.Value(tenantBypassKey{}).(bool); bypass {
+		return nil, false, nil
+	}
+
+	tenant, ok := r.resolver(ctx)
+	if !ok {
+		return nil, false, nil
+	}
+
+	dbo, err := r.GetTenant(tenant)
+	return dbo, true, err
+}
+
+// GetTenant get dbo of tenant, created on first use. concurrent creations of the same tenant share a single dbo
+func (r *TenantRouter) GetTenant(tenant any) (*DBO, error) {
+	key := fmt.Sprint(tenant)
+
+	for {
+		dbo, ok, err := r.touch(key)
+		if err != nil || ok {
+			return dbo, err
+		}
+
+		_, err, _ = r.group.Do(key, func() (any, error) {
+			return nil, r.create(key, tenant)
+		})
+		if err != nil {
+			return nil, err
+		}
+		// dbo of tenant may be evicted before it is touched, create it again
+	}
+}
+
+// touch get dbo of tenant and mark it used, so that the evictor never closes a dbo being returned
+func (r *TenantRouter) touch(key string) (*DBO, bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return nil, false, ErrClosed
+	}
+
+	routed, ok := r.tenants[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	routed.lastUsed = time.Now()
+	return routed.dbo, true, nil
+}
+
+// create create dbo of tenant if it is not routed yet
+func (r *TenantRouter) create(key string, tenant any) error {
+	r.mutex.Lock()
+	_, ok := r.tenants[key]
+	r.mutex.Unlock()
+	if ok {
+		return nil
+	}
+
+	config, err := r.tenantConfig(tenant)
+	if err != nil {
+		log.Warn(context.Background(), "get config of tenant failed", log.Err(err), log.String("tenant", key))
+		return err
+	}
+
+	dbo, err := NewWithConfig(WithConfig(config))
+	if err != nil {
+		log.Warn(context.Background(), "create dbo of tenant failed", log.Err(err), log.String("tenant", key))
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		dbo.Close(context.Background())
+		return ErrClosed
+	}
+
+	for other, routed := range r.tenants {
+		if routed.dbo.dsn == dbo.dsn {
+			dbo.Close(context.Background())
+			err = fmt.Errorf("dbo of tenant %s connects to the same database as tenant %s", key, other)
+			log.Warn(context.Background(), "create dbo of tenant failed", log.Err(err))
+			return err
+		}
+	}
+
+	r.tenants[key] = &tenantDBO{dbo: dbo, lastUsed: time.Now()}
+	log.Info(context.Background(), "dbo of tenant created", log.String("tenant", key))
+	return nil
+}
+
+// tenantConfig get config of tenant from template and options of tenant
+func (r *TenantRouter) tenantConfig(tenant any) (*Config, error) {
+	// slices of template are cloned, options of tenant may append to them
+	config := *r.template
+	config.Observers = slices.Clone(config.Observers)
+	config.SensitiveColumns = slices.Clone(config.SensitiveColumns)
+	for _, option := range r.configure(tenant) {
+		option(&config)
+	}
+
+	// ConnectionString and MySQLConfig take precedence over the structured fields, such as Database
+	// set by WithDatabase, so that the database and params of tenant are applied to them
+	if config.Database != r.template.Database || !maps.Equal(config.Params, r.template.Params) {
+		switch {
+		case config.ConnectionString != "" && config.ConnectionString == r.template.ConnectionString:
+			mc, err := mysql.ParseDSN(config.ConnectionString)
+			if err != nil {
+				return nil, err
+			}
+
+			mc.DBName = config.Database
+			for key, value := range config.Params {
+				if mc.Params == nil {
+					mc.Params = make(map[string]string)
+				}
+				mc.Params[key] = value
+			}
+			config.ConnectionString = mc.FormatDSN()
+		case config.ConnectionString == "" && config.MySQLConfig != nil && config.MySQLConfig == r.template.MySQLConfig:
+			// params are appended to dsn of MySQLConfig
+			config.MySQLConfig = config.MySQLConfig.Clone()
+			config.MySQLConfig.DBName = config.Database
+		}
+	}
+
+	dsn, err := config.DSN()
+	if err != nil {
+		return nil, err
+	}
+
+	if dsn == r.templateDSN {
+		return nil, fmt.Errorf("dbo of tenant %v connects to the database of template, set a database of tenant by configure", tenant)
+	}
+
+	return &config, nil
+}
+
+// Tenants get count of tenants with open dbo
+func (r *TenantRouter) Tenants() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return len(r.tenants)
+}
+
+func (r *TenantRouter) evictLoop() {
+	ticker := time.NewTicker(max(r.idleTimeout/2, minEvictInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			r.evictIdle(now)
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// evictIdle close dbo of tenants unused for idle timeout, transactions in progress are waited
+func (r *TenantRouter) evictIdle(now time.Time) {
+	idle := make(map[string]*DBO)
+	r.mutex.Lock()
+	for key, routed := range r.tenants {
+		if now.Sub(routed.lastUsed) >= r.idleTimeout {
+			idle[key] = routed.dbo
+			delete(r.tenants, key)
+		}
+	}
+	r.mutex.Unlock()
+
+	for key, dbo := range idle {
+		ctx, cancel := context.WithTimeout(context.Background(), dbo.config.TransactionTimeout)
+		err := dbo.Close(ctx)
+		cancel()
+		if err != nil {
+			log.Warn(ctx, "close idle dbo of tenant failed", log.Err(err), log.String("tenant", key))
+			continue
+		}
+
+		log.Info(ctx, "idle dbo of tenant closed", log.String("tenant", key))
+	}
+}
+
+// Close close dbo of all tenants, waiting for transactions in progress until ctx is done
+func (r *TenantRouter) Close(ctx context.Context) error {
+	r.mutex.Lock()
+	if r.closed {
+		r.mutex.Unlock()
+		return ErrClosed
+	}
+	r.closed = true
+	tenants := r.tenants
+	r.tenants = make(map[string]*tenantDBO)
+	r.mutex.Unlock()
+
+	close(r.stop)
+
+	errs := make([]error, 0)
+	for key, routed := range tenants {
+		err := routed.dbo.Close(ctx)
+		if err != nil {
+			log.Warn(ctx, "close dbo of tenant failed", log.Err(err), log.String("tenant", key))
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
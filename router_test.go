@@ -0,0 +1,106 @@
+package dbo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestTenantRouter(t *testing.T) {
+	template := getDefaultConfig()
+	template.Host = "127.0.0.1"
+	template.User = "root"
+	// connect in background, so that no database is needed
+	template.ConnectAsync = true
+
+	resolver := func(ctx context.Context) (any, bool) {
+		tenant, ok := ctx.Value(tenantKey{}).(int64)
+		return tenant, ok
+	}
+	router, err := NewTenantRouter(template, resolver, func(tenant any) []Option {
+		return []Option{WithDatabase(fmt.Sprintf("tenant_%v", tenant)), WithMaxOpenConns(5)}
+	}, time.Hour)
+	if err != nil {
+		t.Fatalf("NewTenantRouter() error = %v", err)
+	}
+	defer router.Close(context.Background())
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, int64(7))
+	var dbo *DBO
+	dbo, err = router.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if dbo.config.Database != "tenant_7" || dbo.config.MaxOpenConns != 5 || template.Database != "" {
+		t.Errorf("config of tenant = %s %d, template = %s", dbo.config.Database, dbo.config.MaxOpenConns, template.Database)
+	}
+
+	same, _ := router.GetTenant(int64(7))
+	other, _ := router.GetTenant(int64(8))
+	if same != dbo || other == dbo || router.Tenants() != 2 {
+		t.Errorf("dbo of tenant should be created once per tenant, tenants = %d", router.Tenants())
+	}
+
+	_, err = router.Get(context.Background())
+	if !errors.Is(err, ErrTenantRequired) {
+		t.Errorf("Get() without tenant error = %v, want %v", err, ErrTenantRequired)
+	}
+
+	router.evictIdle(time.Now().Add(2 * time.Hour))
+	if router.Tenants() != 0 || !dbo.isClosed() {
+		t.Errorf("idle dbo should be closed, tenants = %d", router.Tenants())
+	}
+
+	created, _ := router.GetTenant(int64(7))
+	if created == dbo {
+		t.Errorf("dbo of evicted tenant should be created again")
+	}
+}
+
+func TestTenantRouterConnectionString(t *testing.T) {
+	template := getDefaultConfig()
+	template.ConnectionString = "root:123456@tcp(127.0.0.1:3306)/testdb?parseTime=true"
+	template.ConnectAsync = true
+
+	resolver := func(ctx context.Context) (any, bool) {
+		return nil, false
+	}
+	router, err := NewTenantRouter(template, resolver, func(tenant any) []Option {
+		if tenant == "shared" {
+			return []Option{WithMaxOpenConns(5)}
+		}
+
+		// tenants a and b are configured with the same database by mistake
+		return []Option{WithDatabase("tenant_db")}
+	}, 0)
+	if err != nil {
+		t.Fatalf("NewTenantRouter() error = %v", err)
+	}
+	defer router.Close(context.Background())
+
+	dbo, err := router.GetTenant("a")
+	if err != nil {
+		t.Fatalf("GetTenant() error = %v", err)
+	}
+
+	// the database of tenant is applied to the connection string of template
+	mc, err := mysql.ParseDSN(dbo.dsn)
+	if err != nil || mc.DBName != "tenant_db" || !mc.ParseTime {
+		t.Errorf("dsn of tenant = %s, want database tenant_db", dbo.dsn)
+	}
+
+	_, err = router.GetTenant("b")
+	if err == nil {
+		t.Errorf("GetTenant() should fail for tenant connecting to the database of another tenant")
+	}
+
+	_, err = router.GetTenant("shared")
+	if err == nil {
+		t.Errorf("GetTenant() should fail for tenant connecting to the database of template")
+	}
+}
@@ -0,0 +1,258 @@
+package dbo
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pingcap/tidb/pkg/parser"
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/test_driver"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// maskedValue replacement of sensitive values in logs
+const maskedValue = "******"
+
+var (
+	// sensitiveTables columns tagged with dbo:"sensitive", by table name
+	sensitiveTables sync.Map
+	// sensitiveTableCount count of tables with tagged columns
+	sensitiveTableCount atomic.Int64
+)
+
+// registerSensitiveColumns remember columns of sch tagged with dbo:"sensitive", so that logged sql of the table can be masked
+func registerSensitiveColumns(sch *schema.Schema) {
+	if _, ok := sensitiveTables.Load(sch.Table); ok {
+		return
+	}
+
+	columns := make(map[string]struct{})
+	for _, field := range sch.Fields {
+		if field.DBName != "" && isSensitiveField(field) {
+			columns[field.DBName] = struct{}{}
+		}
+	}
+
+	_, loaded := sensitiveTables.LoadOrStore(sch.Table, columns)
+	if !loaded && len(columns) > 0 {
+		sensitiveTableCount.Add(1)
+	}
+}
+
+// registerStatementSensitiveColumns register tagged columns of statement schema before it is executed
+func registerStatementSensitiveColumns(db *gorm.DB) {
+	if db.Statement.Schema != nil {
+		registerSensitiveColumns(db.Statement.Schema)
+	}
+}
+
+func isSensitiveField(field *schema.Field) bool {
+	return slices.Contains(strings.Split(field.Tag.Get("dbo"), ","), "sensitive")
+}
+
+// sensitiveColumns columns masked in logs, configured as "column" or "table.column"
+type sensitiveColumns map[string]struct{}
+
+func newSensitiveColumns(columns []string) sensitiveColumns {
+	s := make(sensitiveColumns, len(columns))
+	for _, column := range columns {
+		s[strings.ToLower(strings.TrimSpace(column))] = struct{}{}
+	}
+
+	return s
+}
+
+// contains check whether column of table is configured or tagged as sensitive
+func (s sensitiveColumns) contains(table, column string) bool {
+	column = strings.ToLower(column)
+	if _, ok := s[column]; ok {
+		return true
+	}
+
+	if _, ok := s[strings.ToLower(table)+"."+column]; ok {
+		return true
+	}
+
+	columns, ok := sensitiveTables.Load(table)
+	if !ok {
+		return false
+	}
+
+	_, ok = columns.(map[string]struct{})[column]
+	return ok
+}
+
+// filterParams replace sql parameters bound to sensitive columns with masked value.
+// all parameters are masked if sql can not be parsed
+func (s sensitiveColumns) filterParams(sql string, params []any) []any {
+	if len(params) == 0 || (len(s) == 0 && sensitiveTableCount.Load() == 0) {
+		return params
+	}
+
+	stmts, _, err := parser.New().Parse(sql, "", "")
+	if err != nil {
+		masked := make([]any, len(params))
+		for index := range masked {
+			masked[index] = maskedValue
+		}
+		return masked
+	}
+
+	visitor := &paramVisitor{columns: make(map[int]*ast.ColumnName)}
+	for _, stmt := range stmts {
+		stmt.Accept(visitor)
+	}
+	slices.Sort(visitor.offsets)
+
+	var masked []any
+	for index, offset := range visitor.offsets {
+		column, ok := visitor.columns[offset]
+		if !ok || index >= len(params) {
+			continue
+		}
+
+		table := column.Table.O
+		if table == "" {
+			table = visitor.table
+		}
+
+		if s.contains(table, column.Name.O) {
+			if masked == nil {
+				masked = slices.Clone(params)
+			}
+			masked[index] = maskedValue
+		}
+	}
+
+	if masked == nil {
+		return params
+	}
+
+	return masked
+}
+
+// maskValue get fields of value as a map with sensitive fields masked, value is returned as it is if nothing is sensitive
+func (s sensitiveColumns) maskValue(sch *schema.Schema, value any) any {
+	masks := make([]bool, len(sch.Fields))
+	sensitive := false
+	for index, field := range sch.Fields {
+		masks[index] = field.DBName != "" && (isSensitiveField(field) || s.contains(sch.Table, field.DBName))
+		sensitive = sensitive || masks[index]
+	}
+
+	if !sensitive {
+		return value
+	}
+
+	mask := func(rv reflect.Value) any {
+		for rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				return nil
+			}
+			rv = rv.Elem()
+		}
+
+		if rv.Kind() != reflect.Struct || rv.Type() != sch.ModelType {
+			return rv.Interface()
+		}
+
+		fields := make(map[string]any, len(sch.Fields))
+		for index, field := range sch.Fields {
+			if field.DBName == "" {
+				continue
+			}
+
+			if masks[index] {
+				fields[field.Name] = maskedValue
+				continue
+			}
+
+			fields[field.Name] = rv.FieldByIndex(field.StructField.Index).Interface()
+		}
+
+		return fields
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(value))
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return mask(rv)
+	}
+
+	values := make([]any, rv.Len())
+	for index := range values {
+		values[index] = mask(rv.Index(index))
+	}
+
+	return values
+}
+
+// paramVisitor collect parameter markers and the columns they are bound to
+type paramVisitor struct {
+	// table the first table of statement, for columns without table name
+	table   string
+	offsets []int
+	columns map[int]*ast.ColumnName
+}
+
+func (v *paramVisitor) Enter(n ast.Node) (ast.Node, bool) {
+	switch node := n.(type) {
+	case *test_driver.ParamMarkerExpr:
+		v.offsets = append(v.offsets, node.Offset)
+	case *ast.TableName:
+		if v.table == "" {
+			v.table = node.Name.O
+		}
+	case *ast.InsertStmt:
+		for _, list := range node.Lists {
+			for index, expr := range list {
+				if index < len(node.Columns) {
+					v.bind(node.Columns[index], expr)
+				}
+			}
+		}
+		v.bindAssignments(node.OnDuplicate)
+	case *ast.UpdateStmt:
+		v.bindAssignments(node.List)
+	case *ast.BinaryOperationExpr:
+		v.bindExpr(node.L, node.R)
+		v.bindExpr(node.R, node.L)
+	case *ast.PatternInExpr:
+		for _, expr := range node.List {
+			v.bindExpr(node.Expr, expr)
+		}
+	case *ast.PatternLikeOrIlikeExpr:
+		v.bindExpr(node.Expr, node.Pattern)
+	case *ast.BetweenExpr:
+		v.bindExpr(node.Expr, node.Left)
+		v.bindExpr(node.Expr, node.Right)
+	}
+
+	return n, false
+}
+
+func (v *paramVisitor) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
+
+func (v *paramVisitor) bindAssignments(assignments []*ast.Assignment) {
+	for _, assignment := range assignments {
+		v.bind(assignment.Column, assignment.Expr)
+	}
+}
+
+// bindExpr bind parameter marker to column if column is a column name expression
+func (v *paramVisitor) bindExpr(column, expr ast.ExprNode) {
+	if name, ok := column.(*ast.ColumnNameExpr); ok {
+		v.bind(name.Name, expr)
+	}
+}
+
+func (v *paramVisitor) bind(column *ast.ColumnName, expr ast.ExprNode) {
+	if marker, ok := expr.(*test_driver.ParamMarkerExpr); ok {
+		v.columns[marker.Offset] = column
+	}
+}
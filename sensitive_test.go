@@ -0,0 +1,102 @@
+package dbo
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm/schema"
+)
+
+type sensitiveUser struct {
+	ID    int64  `gorm:"column:id"`
+	Name  string `gorm:"column:name"`
+	Phone string `gorm:"column:phone" dbo:"sensitive"`
+	Token string `gorm:"column:token"`
+}
+
+func TestSensitiveColumnsFilterParams(t *testing.T) {
+	sch, err := schema.Parse(&sensitiveUser{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registerSensitiveColumns(sch)
+
+	columns := newSensitiveColumns([]string{"sensitive_users.token"})
+	tests := []struct {
+		name   string
+		sql    string
+		params []any
+		want   []any
+	}{
+		{
+			name:   "insert",
+			sql:    "INSERT INTO `sensitive_users` (`name`,`phone`,`token`) VALUES (?,?,?),(?,?,?)",
+			params: []any{"a", "13800000000", "t1", "b", "13900000000", "t2"},
+			want:   []any{"a", maskedValue, maskedValue, "b", maskedValue, maskedValue},
+		},
+		{
+			name:   "update",
+			sql:    "UPDATE `sensitive_users` SET `name`=?,`phone`=? WHERE `id` = ?",
+			params: []any{"a", "13800000000", 1},
+			want:   []any{"a", maskedValue, 1},
+		},
+		{
+			name:   "select",
+			sql:    "SELECT * FROM `sensitive_users` WHERE id > ? and phone in (?,?) and name like ?",
+			params: []any{1, "13800000000", "13900000000", "a%"},
+			want:   []any{1, maskedValue, maskedValue, "a%"},
+		},
+		{
+			name:   "other table",
+			sql:    "SELECT * FROM `table_a` WHERE phone = ?",
+			params: []any{"13800000000"},
+			want:   []any{"13800000000"},
+		},
+		{
+			name:   "invalid sql",
+			sql:    "SELECT * FROM",
+			params: []any{1},
+			want:   []any{maskedValue},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := columns.filterParams(tt.sql, tt.params); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filterParams() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSensitiveColumnsMaskValue(t *testing.T) {
+	sch, err := schema.Parse(&sensitiveUser{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := sensitiveColumns{}.maskValue(sch, []*sensitiveUser{{ID: 1, Name: "a", Phone: "13800000000", Token: "t1"}})
+	want := []any{map[string]any{"ID": int64(1), "Name": "a", "Phone": maskedValue, "Token": "t1"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("maskValue() = %v, want %v", got, want)
+	}
+}
+
+type phoneCondition string
+
+func (c phoneCondition) GetConditions() ([]string, []any) {
+	return []string{"name = ?", "phone = ?"}, []any{"a", string(c)}
+}
+
+func TestMaskCondition(t *testing.T) {
+	config := getDefaultConfig()
+	db := dryRun(context.Background(), newTestDBO(t, config))
+
+	got := db.maskCondition(&sensitiveUser{}, phoneCondition("13800000000"))
+	want := map[string]any{"wheres": []string{"name = ?", "phone = ?"}, "parameters": []any{"a", maskedValue}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("maskCondition() = %v, want %v", got, want)
+	}
+}
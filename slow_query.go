@@ -0,0 +1,266 @@
+package dbo
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nzai/log"
+	"gorm.io/gorm"
+)
+
+// explainTimeout timeout of explaining a slow query
+const explainTimeout = 5 * time.Second
+
+// SlowQuery statement slower than Config.SlowThreshold
+type SlowQuery struct {
+	SQL string
+	// Args parameters of SQL, parameters bound to sensitive columns are masked
+	Args     []any
+	Duration time.Duration
+	Rows     int64
+	Caller   string
+	// Plan explain result of slow select, nil if the statement is not explained
+	Plan []ExplainRow
+	// FullScan any table of the plan is accessed by full table scan
+	FullScan bool
+	// Filesort any table of the plan uses filesort
+	Filesort bool
+}
+
+// ExplainRow row of mysql EXPLAIN
+type ExplainRow struct {
+	ID           string
+	SelectType   string
+	Table        string
+	Type         string
+	PossibleKeys string
+	Key          string
+	KeyLen       string
+	Ref          string
+	Rows         int64
+	Filtered     float64
+	Extra        string
+}
+
+// SlowQueryHandler handle slow query, called in background if the query is explained
+type SlowQueryHandler func(ctx context.Context, query *SlowQuery)
+
+// slowQueryMonitor call slow query handler after statements slower than threshold, and explain slow selects
+type slowQueryMonitor struct {
+	threshold time.Duration
+	handler   SlowQueryHandler
+	sensitive sensitiveColumns
+
+	explain         bool
+	explainInterval time.Duration
+	// lastExplain unix nano of the last explain, at most one explain in every explainInterval
+	lastExplain atomic.Int64
+
+	driverName  string
+	dsn         string
+	explainOnce sync.Once
+	explainDB   *sql.DB
+	explainErr  error
+}
+
+// newSlowQueryMonitor create monitor, nil if neither handler nor explain is configured
+func newSlowQueryMonitor(config *Config, dsn string, sensitive sensitiveColumns) *slowQueryMonitor {
+	if config.SlowQueryHandler == nil && !config.ExplainSlowQueries {
+		return nil
+	}
+
+	m := &slowQueryMonitor{
+		threshold:       config.SlowThreshold,
+		handler:         config.SlowQueryHandler,
+		sensitive:       sensitive,
+		explain:         config.ExplainSlowQueries,
+		explainInterval: config.ExplainInterval,
+		driverName:      config.DBType.DriverName(),
+		dsn:             dsn,
+	}
+	if m.handler == nil {
+		m.handler = logSlowQuery
+	}
+
+	return m
+}
+
+// check call handler if the statement is slow
+func (m *slowQueryMonitor) check(db *gorm.DB) {
+	if m == nil || m.threshold <= 0 || db.DryRun || db.Statement.SQL.Len() == 0 {
+		return
+	}
+
+	duration := statementDuration(db)
+	if duration <= m.threshold {
+		return
+	}
+
+	statement := db.Statement.SQL.String()
+	query := &SlowQuery{
+		SQL:      statement,
+		Args:     m.sensitive.filterParams(statement, db.Statement.Vars),
+		Duration: duration,
+		Rows:     db.RowsAffected,
+		Caller:   caller(),
+	}
+
+	ctx := db.Statement.Context
+	if !m.shouldExplain(statement) {
+		m.handler(ctx, query)
+		return
+	}
+
+	// explain in background with the original parameters, the statement context may be done soon
+	vars := slices.Clone(db.Statement.Vars)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), explainTimeout)
+		defer cancel()
+
+		err := m.explainQuery(ctx, query, vars)
+		if err != nil {
+			log.Warn(ctx, "explain slow query failed", log.Err(err), log.String("sql", statement))
+		}
+
+		m.handler(ctx, query)
+	}()
+}
+
+// shouldExplain check whether statement is a select and the explain rate limit is not exceeded
+func (m *slowQueryMonitor) shouldExplain(statement string) bool {
+	if !m.explain || !strings.HasPrefix(strings.ToUpper(strings.TrimSpace(statement)), "SELECT") {
+		return false
+	}
+
+	now := time.Now().UnixNano()
+	last := m.lastExplain.Load()
+	if last != 0 && now-last < int64(m.explainInterval) {
+		return false
+	}
+
+	return m.lastExplain.CompareAndSwap(last, now)
+}
+
+// explainQuery run EXPLAIN on a separate connection, so that an exhausted pool does not block explaining
+func (m *slowQueryMonitor) explainQuery(ctx context.Context, query *SlowQuery, vars []any) error {
+	m.explainOnce.Do(func() {
+		m.explainDB, m.explainErr = sql.Open(m.driverName, m.dsn)
+		if m.explainErr == nil {
+			m.explainDB.SetMaxOpenConns(1)
+			m.explainDB.SetConnMaxIdleTime(m.explainInterval)
+		}
+	})
+	if m.explainErr != nil {
+		return m.explainErr
+	}
+
+	rows, err := m.explainDB.QueryContext(ctx, "EXPLAIN "+query.SQL, vars...)
+	if err != nil {
+		return err
+	}
+
+	query.Plan, err = scanExplainRows(rows)
+	if err != nil {
+		return err
+	}
+
+	query.FullScan, query.Filesort = analyzePlan(query.Plan)
+	return nil
+}
+
+// close close the explain connection
+func (m *slowQueryMonitor) close() error {
+	if m == nil {
+		return nil
+	}
+
+	// explaining after closing fails with ErrClosed
+	m.explainOnce.Do(func() {
+		m.explainErr = ErrClosed
+	})
+	if m.explainDB == nil {
+		return nil
+	}
+
+	return m.explainDB.Close()
+}
+
+// scanExplainRows scan and close rows of EXPLAIN, columns are matched by name since they vary by mysql version
+func scanExplainRows(rows *sql.Rows) ([]ExplainRow, error) {
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := make([]ExplainRow, 0)
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]any, len(columns))
+		for index := range values {
+			dest[index] = &values[index]
+		}
+
+		err = rows.Scan(dest...)
+		if err != nil {
+			return nil, err
+		}
+
+		fields := make(map[string]string, len(columns))
+		for index, column := range columns {
+			fields[strings.ToLower(column)] = values[index].String
+		}
+		plan = append(plan, newExplainRow(fields))
+	}
+
+	return plan, rows.Err()
+}
+
+func newExplainRow(fields map[string]string) ExplainRow {
+	rows, _ := strconv.ParseInt(fields["rows"], 10, 64)
+	filtered, _ := strconv.ParseFloat(fields["filtered"], 64)
+	return ExplainRow{
+		ID:           fields["id"],
+		SelectType:   fields["select_type"],
+		Table:        fields["table"],
+		Type:         fields["type"],
+		PossibleKeys: fields["possible_keys"],
+		Key:          fields["key"],
+		KeyLen:       fields["key_len"],
+		Ref:          fields["ref"],
+		Rows:         rows,
+		Filtered:     filtered,
+		Extra:        fields["extra"],
+	}
+}
+
+// analyzePlan check whether any table is accessed by full table scan, or sorted by filesort
+func analyzePlan(plan []ExplainRow) (fullScan bool, filesort bool) {
+	for _, row := range plan {
+		fullScan = fullScan || strings.EqualFold(row.Type, "ALL")
+		filesort = filesort || strings.Contains(row.Extra, "Using filesort")
+	}
+
+	return fullScan, filesort
+}
+
+// logSlowQuery default slow query handler when explain is enabled, instead of the slow sql warning of sql logger
+func logSlowQuery(ctx context.Context, query *SlowQuery) {
+	log.Warn(ctx, "slow sql",
+		log.String("logType", "sql"),
+		log.String("sql", query.SQL),
+		log.Any("args", query.Args),
+		log.Duration("duration", query.Duration),
+		log.Int64("rowsAffected", query.Rows),
+		log.String("caller", query.Caller),
+		log.Any("plan", query.Plan),
+		log.Bool("fullScan", query.FullScan),
+		log.Bool("filesort", query.Filesort))
+}
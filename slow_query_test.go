@@ -0,0 +1,37 @@
+package dbo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnalyzePlan(t *testing.T) {
+	plan := []ExplainRow{
+		newExplainRow(map[string]string{"id": "1", "table": "table_a", "type": "ref", "key": "idx_name", "rows": "10"}),
+		newExplainRow(map[string]string{"id": "1", "table": "table_b", "type": "ALL", "rows": "1000", "extra": "Using where; Using filesort"}),
+	}
+
+	fullScan, filesort := analyzePlan(plan)
+	if !fullScan || !filesort {
+		t.Errorf("analyzePlan() = %v, %v, want true, true", fullScan, filesort)
+	}
+
+	if plan[1].Rows != 1000 {
+		t.Errorf("rows = %d, want 1000", plan[1].Rows)
+	}
+}
+
+func TestSlowQueryMonitorShouldExplain(t *testing.T) {
+	m := &slowQueryMonitor{explain: true, explainInterval: time.Hour}
+	if m.shouldExplain("UPDATE table_a SET name = ?") {
+		t.Error("shouldExplain() = true for update")
+	}
+
+	if !m.shouldExplain("select * from table_a") {
+		t.Error("shouldExplain() = false for the first select")
+	}
+
+	if m.shouldExplain("select * from table_a") {
+		t.Error("shouldExplain() = true within explain interval")
+	}
+}
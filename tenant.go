@@ -0,0 +1,369 @@
+package dbo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// TenantResolver get tenant id from context, ok is false if ctx has no tenant
+type TenantResolver func(ctx context.Context) (tenant any, ok bool)
+
+type tenantBypassKey struct{}
+
+// WithoutTenant bypass tenant scoping of statements executed with ctx, such as admin jobs across tenants
+func WithoutTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tenantBypassKey{}, true)
+}
+
+// tenantFields field tagged with dbo:"tenant" by model type, nil if the model has no tenant column.
+// structs mapped to the same table may tag different fields
+var tenantFields sync.Map
+
+// getTenantField get the tenant field of sch, nil if no field is tagged with dbo:"tenant"
+func getTenantField(sch *schema.Schema) *schema.Field {
+	if field, ok := tenantFields.Load(sch.ModelType); ok {
+		return field.(*schema.Field)
+	}
+
+	var tenantField *schema.Field
+	for _, field := range sch.Fields {
+		if field.DBName != "" && slices.Contains(strings.Split(field.Tag.Get("dbo"), ","), "tenant") {
+			tenantField = field
+			break
+		}
+	}
+
+	tenantFields.Store(sch.ModelType, tenantField)
+	return tenantField
+}
+
+// tenantScope scope statements of tenant entities by the tenant resolved from context
+type tenantScope struct {
+	resolver TenantResolver
+}
+
+// newTenantScope create tenant scope, nil if resolver is not configured
+func newTenantScope(config *Config) *tenantScope {
+	if config.TenantResolver == nil {
+		return nil
+	}
+
+	return &tenantScope{resolver: config.TenantResolver}
+}
+
+// resolve get the tenant field of sch and the tenant of ctx, field is nil if the statement is not scoped
+func (t *tenantScope) resolve(ctx context.Context, sch *schema.Schema) (*schema.Field, any, error) {
+	if t == nil || sch == nil {
+		return nil, nil, nil
+	}
+
+	field := getTenantField(sch)
+	if field == nil {
+		return nil, nil, nil
+	}
+
+	if bypass, _ := ctx.Value(tenantBypassKey{}).(bool); bypass {
+		return nil, nil, nil
+	}
+
+	tenant, ok := t.resolver(ctx)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: table %s", ErrTenantRequired, sch.Table)
+	}
+
+	return field, tenant, nil
+}
+
+// tenantCondition tenant predicate of field
+func tenantCondition(field *schema.Field, tenant any) clause.Expression {
+	return clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: tenant}
+}
+
+// query add tenant predicate to queries of tenant entities
+func (t *tenantScope) query(db *gorm.DB) {
+	// statements with sql, such as Raw, are not scoped
+	if db.Error != nil || db.Statement.SQL.Len() > 0 {
+		return
+	}
+
+	field, tenant, err := t.resolve(db.Statement.Context, db.Statement.Schema)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+
+	if field != nil {
+		db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{tenantCondition(field, tenant)}})
+	}
+}
+
+// update add tenant predicate to updates of tenant entities, and keep the tenant of updated entities
+func (t *tenantScope) update(db *gorm.DB) {
+	field, tenant := t.modify(db)
+	if field == nil {
+		return
+	}
+
+	// Save updates all fields, the tenant of entity is set or checked as inserting
+	assignTenant(db, field, tenant)
+	if db.Error != nil {
+		return
+	}
+
+	if values, ok := db.Statement.Dest.(map[string]any); ok {
+		for _, key := range []string{field.DBName, field.Name} {
+			value, ok := values[key]
+			if ok && fmt.Sprint(value) != fmt.Sprint(tenant) {
+				db.AddError(fmt.Errorf("%w: update tenant of table %s to %v", ErrTenantMismatch, db.Statement.Schema.Table, value))
+			}
+		}
+		return
+	}
+
+	// Updates of struct, such as Model(&entity).Updates(T{...}), update its non-zero or selected fields
+	value, ok := updatedTenant(db, field)
+	if ok && fmt.Sprint(value) != fmt.Sprint(tenant) {
+		db.AddError(fmt.Errorf("%w: update tenant of table %s to %v", ErrTenantMismatch, db.Statement.Schema.Table, value))
+	}
+}
+
+// updatedTenant get the tenant updated by struct dest of db, ok is false if the tenant is not updated
+func updatedTenant(db *gorm.DB, field *schema.Field) (any, bool) {
+	rv := reflect.ValueOf(db.Statement.Dest)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	// dest may be another struct mapped to the table
+	destField := field
+	if rv.Type() != db.Statement.Schema.ModelType {
+		stmt := &gorm.Statement{DB: db}
+		if stmt.Parse(db.Statement.Dest) != nil {
+			return nil, false
+		}
+
+		destField = stmt.Schema.LookUpField(field.DBName)
+		if destField == nil {
+			return nil, false
+		}
+	}
+
+	value, zero := destField.ValueOf(db.Statement.Context, rv)
+	if zero {
+		selected, restricted := db.Statement.SelectAndOmitColumns(false, true)
+		if !restricted || !selected[field.DBName] {
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+// delete add tenant predicate to deletes of tenant entities
+func (t *tenantScope) delete(db *gorm.DB) {
+	t.modify(db)
+}
+
+// modify add tenant predicate to updates and deletes, get the tenant field and tenant if the statement is scoped
+func (t *tenantScope) modify(db *gorm.DB) (*schema.Field, any) {
+	if db.Error != nil || db.Statement.SQL.Len() > 0 {
+		return nil, nil
+	}
+
+	field, tenant, err := t.resolve(db.Statement.Context, db.Statement.Schema)
+	if err != nil {
+		db.AddError(err)
+		return nil, nil
+	}
+
+	// updates and deletes without conditions are left to gorm, which rejects them with ErrMissingWhereClause
+	if field == nil || !hasConditions(db) {
+		return nil, nil
+	}
+
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{tenantCondition(field, tenant)}})
+	return field, tenant
+}
+
+// hasConditions check whether update or delete has conditions, including primary keys of the model
+// which are added by gorm later
+func hasConditions(db *gorm.DB) bool {
+	if db.AllowGlobalUpdate {
+		return true
+	}
+
+	if where, ok := db.Statement.Clauses["WHERE"].Expression.(clause.Where); ok && len(where.Exprs) > 0 {
+		return true
+	}
+
+	_, values := schema.GetIdentityFieldValuesMap(db.Statement.Context, db.Statement.ReflectValue, db.Statement.Schema.PrimaryFields)
+	return len(values) > 0
+}
+
+// scopeTenant add tenant predicate of model to db, for statements built by table name without model
+// which are not scoped by callbacks
+func (s *DBContext) scopeTenant(model any) {
+	if s.dbo == nil || s.dbo.tenant == nil {
+		return
+	}
+
+	sch, err := s.GetSchema(model)
+	if err != nil {
+		return
+	}
+
+	field, tenant, err := s.dbo.tenant.resolve(s.Statement.Context, sch)
+	if err != nil {
+		s.AddError(err)
+		return
+	}
+
+	if field != nil {
+		s.DB = s.Where(tenantCondition(field, tenant))
+	}
+}
+
+// inTenant check whether entity read from cache belongs to the tenant of ctx
+func (s *DBContext) inTenant(ctx context.Context, value any) (bool, error) {
+	if s.dbo == nil || s.dbo.tenant == nil {
+		return true, nil
+	}
+
+	sch, err := s.GetSchema(value)
+	if err != nil {
+		return true, nil
+	}
+
+	field, tenant, err := s.dbo.tenant.resolve(ctx, sch)
+	if err != nil || field == nil {
+		return err == nil, err
+	}
+
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+
+	entityTenant, _ := field.ValueOf(ctx, rv)
+	return fmt.Sprint(entityTenant) == fmt.Sprint(tenant), nil
+}
+
+// removeOtherTenants remove cached entities of other tenants than ctx, cached entities are shared by tenants
+func removeOtherTenants[T any](ctx context.Context, db *DBContext, entities map[string]T) error {
+	for key, entity := range entities {
+		ok, err := db.inTenant(ctx, entity)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			delete(entities, key)
+		}
+	}
+
+	return nil
+}
+
+// create set tenant of inserted tenant entities, entities of other tenants are rejected
+func (t *tenantScope) create(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+
+	field, tenant, err := t.resolve(db.Statement.Context, db.Statement.Schema)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+
+	if field == nil {
+		return
+	}
+
+	assignTenant(db, field, tenant)
+
+	// upserts, such as Save of entities missing in the tenant, must not overwrite rows of other tenants
+	if c, ok := db.Statement.Clauses["ON CONFLICT"]; ok {
+		onConflict, ok := c.Expression.(clause.OnConflict)
+		if ok && (onConflict.UpdateAll || len(onConflict.DoUpdates) > 0) {
+			db.Statement.AddClause(tenantOnConflict(db.Statement.Schema, field, tenant, onConflict))
+		}
+	}
+}
+
+// assignTenant set tenant of entities with zero tenant, entities of other tenants are rejected
+func assignTenant(db *gorm.DB, field *schema.Field, tenant any) {
+	ctx := db.Statement.Context
+	assign := func(rv reflect.Value) {
+		if rv.Kind() != reflect.Struct || !rv.CanAddr() {
+			return
+		}
+
+		value, zero := field.ValueOf(ctx, rv)
+		if zero {
+			db.AddError(field.Set(ctx, rv, tenant))
+			return
+		}
+
+		if fmt.Sprint(value) != fmt.Sprint(tenant) {
+			db.AddError(fmt.Errorf("%w: entity of tenant %v in table %s of tenant %v", ErrTenantMismatch, value, db.Statement.Schema.Table, tenant))
+		}
+	}
+
+	rv := reflect.Indirect(db.Statement.ReflectValue)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for index := 0; index < rv.Len(); index++ {
+			assign(reflect.Indirect(rv.Index(index)))
+		}
+	case reflect.Struct:
+		assign(rv)
+	}
+}
+
+// tenantOnConflict update conflicted rows only if they belong to tenant, the tenant column is never updated
+func tenantOnConflict(sch *schema.Schema, field *schema.Field, tenant any, onConflict clause.OnConflict) clause.OnConflict {
+	assignments := onConflict.DoUpdates
+	if onConflict.UpdateAll {
+		assignments = make([]clause.Assignment, 0, len(sch.DBNames))
+		for _, name := range sch.DBNames {
+			f := sch.FieldsByDBName[name]
+			if f.Creatable && f.Updatable && !f.PrimaryKey && (!f.HasDefaultValue || f.DefaultValueInterface != nil) && f.AutoCreateTime == 0 {
+				assignments = append(assignments, clause.Assignment{Column: clause.Column{Name: name}, Value: clause.Column{Table: "excluded", Name: name}})
+			}
+		}
+	}
+
+	doUpdates := make([]clause.Assignment, 0, len(assignments))
+	for _, assignment := range assignments {
+		if assignment.Column.Name == field.DBName {
+			continue
+		}
+
+		value := assignment.Value
+		if column, ok := value.(clause.Column); ok && column.Table == "excluded" {
+			value = gorm.Expr("VALUES(?)", clause.Column{Name: column.Name})
+		}
+
+		doUpdates = append(doUpdates, clause.Assignment{
+			Column: assignment.Column,
+			Value:  gorm.Expr("IF(? = ?, ?, ?)", clause.Column{Name: field.DBName}, tenant, value, clause.Column{Name: assignment.Column.Name}),
+		})
+	}
+
+	onConflict.UpdateAll = false
+	onConflict.DoUpdates = doUpdates
+	return onConflict
+}
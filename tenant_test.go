@@ -0,0 +1,129 @@
+package dbo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type tenantKey struct{}
+
+type tenantOrder struct {
+	ID     int64  `gorm:"column:id;primaryKey"`
+	OrgID  int64  `gorm:"column:org_id" dbo:"tenant"`
+	Amount int64  `gorm:"column:amount"`
+	Remark string `gorm:"column:remark"`
+}
+
+func (tenantOrder) TableName() string {
+	return "tenant_order"
+}
+
+// tenantOrderSummary read model of tenant_order without tenant column
+type tenantOrderSummary struct {
+	ID     int64 `gorm:"column:id;primaryKey"`
+	Amount int64 `gorm:"column:amount"`
+}
+
+func (tenantOrderSummary) TableName() string {
+	return "tenant_order"
+}
+
+func TestTenantScope(t *testing.T) {
+	config := getDefaultConfig()
+	config.TenantResolver = func(ctx context.Context) (any, bool) {
+		tenant, ok := ctx.Value(tenantKey{}).(int64)
+		return tenant, ok
+	}
+
+	dbo := newTestDBO(t, config)
+	session := func(ctx context.Context) *gorm.DB {
+		return dryRun(ctx, dbo).DB
+	}
+	ctx := context.WithValue(context.Background(), tenantKey{}, int64(7))
+
+	var orders []tenantOrder
+	stmt := session(ctx).Where("amount > ?", 10).Find(&orders).Statement
+	if !strings.Contains(stmt.SQL.String(), "`tenant_order`.`org_id` = ?") || stmt.Vars[len(stmt.Vars)-1] != int64(7) {
+		t.Errorf("query = %s %v, want tenant predicate", stmt.SQL.String(), stmt.Vars)
+	}
+
+	err := session(context.Background()).Find(&orders).Error
+	if !errors.Is(err, ErrTenantRequired) {
+		t.Errorf("query without tenant error = %v, want %v", err, ErrTenantRequired)
+	}
+
+	stmt = session(WithoutTenant(context.Background())).Find(&orders).Statement
+	if stmt.Error != nil || strings.Contains(stmt.SQL.String(), "WHERE") {
+		t.Errorf("bypassed query = %s %v, want no tenant predicate", stmt.SQL.String(), stmt.Error)
+	}
+
+	// entities without tenant column are not scoped
+	stmt = session(context.Background()).Find(&[]tableA{}).Statement
+	if stmt.Error != nil || strings.Contains(stmt.SQL.String(), "WHERE") {
+		t.Errorf("query of table_a = %s %v, want no tenant predicate", stmt.SQL.String(), stmt.Error)
+	}
+
+	// tenant field is resolved per struct, not per table
+	stmt = session(context.Background()).Find(&[]tenantOrderSummary{}).Statement
+	if stmt.Error != nil || strings.Contains(stmt.SQL.String(), "WHERE") {
+		t.Errorf("query of tenant_order summary = %s %v, want no tenant predicate", stmt.SQL.String(), stmt.Error)
+	}
+
+	stmt = session(ctx).Find(&orders).Statement
+	if !strings.Contains(stmt.SQL.String(), "`tenant_order`.`org_id` = ?") {
+		t.Errorf("query after summary = %s, want tenant predicate", stmt.SQL.String())
+	}
+
+	order := &tenantOrder{Amount: 100}
+	err = session(ctx).Create(order).Error
+	if err != nil || order.OrgID != 7 {
+		t.Errorf("create error = %v, org id = %d, want tenant assigned", err, order.OrgID)
+	}
+
+	err = session(ctx).Create(&tenantOrder{OrgID: 8}).Error
+	if !errors.Is(err, ErrTenantMismatch) {
+		t.Errorf("create in other tenant error = %v, want %v", err, ErrTenantMismatch)
+	}
+
+	stmt = session(ctx).Delete(&tenantOrder{ID: 1}).Statement
+	if !strings.Contains(stmt.SQL.String(), "`tenant_order`.`org_id` = ?") {
+		t.Errorf("delete = %s, want tenant predicate", stmt.SQL.String())
+	}
+
+	// the tenant predicate does not turn a delete without conditions into a tenant wide delete
+	err = session(ctx).Delete(&tenantOrder{}).Error
+	if !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Errorf("delete without conditions error = %v, want %v", err, gorm.ErrMissingWhereClause)
+	}
+
+	err = session(ctx).Model(&tenantOrder{}).Where("id = ?", 1).Updates(map[string]any{"org_id": 8}).Error
+	if !errors.Is(err, ErrTenantMismatch) {
+		t.Errorf("update tenant error = %v, want %v", err, ErrTenantMismatch)
+	}
+
+	err = session(ctx).Model(&tenantOrder{ID: 1}).Updates(tenantOrder{OrgID: 8, Amount: 10}).Error
+	if !errors.Is(err, ErrTenantMismatch) {
+		t.Errorf("update tenant by struct error = %v, want %v", err, ErrTenantMismatch)
+	}
+
+	err = session(ctx).Model(&tenantOrder{ID: 1}).Updates(&tenantOrder{OrgID: 7, Amount: 10}).Error
+	if err != nil {
+		t.Errorf("update by struct of the same tenant error = %v, want nil", err)
+	}
+
+	err = session(ctx).Model(&tenantOrder{ID: 1}).Select("org_id").Updates(tenantOrder{}).Error
+	if !errors.Is(err, ErrTenantMismatch) {
+		t.Errorf("update selected zero tenant error = %v, want %v", err, ErrTenantMismatch)
+	}
+
+	stmt = session(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&tenantOrder{ID: 1, Amount: 100}).Statement
+	statement := stmt.SQL.String()
+	if !strings.Contains(statement, "`amount`=IF(`org_id` = ?, VALUES(`amount`), `amount`)") || strings.Contains(statement, "`org_id`=") {
+		t.Errorf("upsert = %s, want updates guarded by tenant", statement)
+	}
+}
@@ -0,0 +1,67 @@
+package dbo
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"gorm.io/gorm"
+)
+
+// tracerName instrumentation name of dbo spans
+const tracerName = "github.com/nzai/dbo/v2"
+
+// newTracer get tracer of config, spans are not recorded if TracerProvider is nil
+func newTracer(config *Config) trace.Tracer {
+	if config.TracerProvider == nil {
+		return noop.NewTracerProvider().Tracer(tracerName)
+	}
+
+	return config.TracerProvider.Tracer(tracerName)
+}
+
+// startSpan start client span of database operation
+func (s *DBO) startSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	attributes = append(attributes, semconv.DBSystemMySQL)
+	return s.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes...))
+}
+
+// endSpan record classified error and end span, record not found is not an error status
+func endSpan(span trace.Span, err error, attributes ...attribute.KeyValue) {
+	span.SetAttributes(attributes...)
+	if err != nil {
+		span.SetAttributes(attribute.String("dbo.error_type", ErrorType(err)))
+		if !errors.Is(err, ErrRecordNotFound) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+
+	span.End()
+}
+
+// traceStatement set db.statement of the operation span after every statement
+func traceStatement(config *Config) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		span := trace.SpanFromContext(db.Statement.Context)
+		if !span.IsRecording() {
+			return
+		}
+
+		statement := db.Statement.SQL.String()
+		if config.TraceSQLParameters {
+			vars := db.Statement.Vars
+			if filter, ok := db.Logger.(gorm.ParamsFilter); ok {
+				statement, vars = filter.ParamsFilter(db.Statement.Context, statement, vars...)
+			}
+			statement = db.Dialector.Explain(statement, vars...)
+		}
+		span.SetAttributes(semconv.DBStatement(statement))
+	}
+}
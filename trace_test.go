@@ -0,0 +1,87 @@
+package dbo
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type nameCondition string
+
+func (c nameCondition) GetConditions() ([]string, []any) {
+	return []string{"name = ?"}, []any{string(c)}
+}
+
+func TestOperationSpan(t *testing.T) {
+	ctx := context.Background()
+	recorder := tracetest.NewSpanRecorder()
+	config := getDefaultConfig()
+	config.TracerProvider = trace.NewTracerProvider(trace.WithSpanProcessor(recorder))
+
+	dbContext := dryRun(ctx, newTestDBO(t, config))
+
+	_, err := QueryTx[tableA](ctx, dbContext, nameCondition("secret"))
+	if err != nil {
+		t.Fatalf("QueryTx() error = %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Name() != "dbo."+OpQuery {
+		t.Fatalf("spans = %v, want one dbo.query span", spans)
+	}
+
+	attributes := make(map[string]string)
+	for _, attribute := range spans[0].Attributes() {
+		attributes[string(attribute.Key)] = attribute.Value.Emit()
+	}
+
+	if attributes["db.sql.table"] != "table_a" || attributes["db.system"] != "mysql" {
+		t.Errorf("attributes = %v", attributes)
+	}
+
+	statement := attributes["db.statement"]
+	if !strings.HasPrefix(statement, "SELECT") || strings.Contains(statement, "secret") {
+		t.Errorf("db.statement = %q, want sql with redacted parameters", statement)
+	}
+
+	if dbContext.Statement.Context != ctx {
+		t.Errorf("statement context is not restored after operation")
+	}
+}
+
+type transactionRecorder struct {
+	events []TransactionEvent
+}
+
+func (r *transactionRecorder) ObserveOperation(ctx context.Context, event OperationEvent) {}
+
+func (r *transactionRecorder) ObserveTransaction(ctx context.Context, event TransactionEvent) {
+	r.events = append(r.events, event)
+}
+
+func TestFinishTransaction(t *testing.T) {
+	ctx := context.Background()
+	dbo := newTestDBO(t, getDefaultConfig())
+	recorder := &transactionRecorder{}
+	dbo.AddObserver(recorder)
+
+	_, span := dbo.tracer.Start(ctx, "dbo.transaction")
+	dbo.finishTransaction(ctx, span, time.Now(), true, nil)
+	_, span = dbo.tracer.Start(ctx, "dbo.transaction")
+	dbo.finishTransaction(ctx, span, time.Now(), true, newError(OpCommit, "", time.Now(), driver.ErrBadConn))
+
+	if len(recorder.events) != 2 || recorder.events[0].Result != TransactionCommit {
+		t.Fatalf("events = %+v, want committed transaction", recorder.events)
+	}
+
+	// failed commit is an error classified by its type, not a commit
+	event := recorder.events[1]
+	if event.Result != TransactionError || ErrorType(event.Err) != "connection" {
+		t.Errorf("failed commit = %s %s, want %s connection", event.Result, ErrorType(event.Err), TransactionError)
+	}
+}
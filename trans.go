@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/nzai/log"
 )
@@ -11,18 +12,30 @@ import (
 // GetTrans begin a transaction
 func GetTrans(ctx context.Context, fn func(ctx context.Context, tx *DBContext) error) error {
 	log.Debug(ctx, "begin transaction")
+	start := time.Now()
 
-	dbo, err := GetGlobal()
+	dbo, err := getDBO(ctx)
 	if err != nil {
 		return err
 	}
 
+	err = dbo.beginTransaction()
+	if err != nil {
+		return err
+	}
+	defer dbo.endTransaction()
+
+	if dbo.nPlusOne != nil {
+		ctx = TrackQueries(ctx)
+	}
+
+	ctx, span := dbo.startSpan(ctx, "dbo.transaction")
 	ctxWithTimeout, cancel := context.WithTimeout(ctx, dbo.config.TransactionTimeout)
 	defer cancel()
 
-	db, err := GetDB(ctxWithTimeout)
-	if err != nil {
-		return err
+	db := dbo.GetDB(ctxWithTimeout)
+	if dbo.cache != nil {
+		db.invalidations = &cacheInvalidations{}
 	}
 
 	//db.DB = db.BeginTx(ctxWithTimeout, &sql.TxOptions{})
@@ -56,15 +69,21 @@ func GetTrans(ctx context.Context, fn func(ctx context.Context, tx *DBContext) e
 		} else {
 			log.Debug(ctxWithTimeout, "rollback transaction successfully")
 		}
+		dbo.finishTransaction(ctxWithTimeout, span, start, false, err)
 		return err
 	}
 
 	err = db.Commit().Error
 	if err != nil {
+		err = newError(OpCommit, "", start, err)
 		log.Warn(ctxWithTimeout, "commit transaction failed", log.Err(err))
+		dbo.finishTransaction(ctxWithTimeout, span, start, true, err)
 		return err
 	}
 
+	dbo.finishTransaction(ctxWithTimeout, span, start, true, nil)
+	db.commitInvalidations(ctx)
+
 	log.Debug(ctxWithTimeout, "commit transaction successfully")
 
 	return nil
@@ -78,19 +97,31 @@ type transactionResult[T any] struct {
 // GetTransResult begin a transaction, get result of callback
 func GetTransResult[T any](ctx context.Context, fn func(ctx context.Context, tx *DBContext) (T, error)) (T, error) {
 	log.Debug(ctx, "begin transaction")
+	start := time.Now()
 	var value T
 
-	dbo, err := GetGlobal()
+	dbo, err := getDBO(ctx)
 	if err != nil {
 		return value, err
 	}
 
+	err = dbo.beginTransaction()
+	if err != nil {
+		return value, err
+	}
+	defer dbo.endTransaction()
+
+	if dbo.nPlusOne != nil {
+		ctx = TrackQueries(ctx)
+	}
+
+	ctx, span := dbo.startSpan(ctx, "dbo.transaction")
 	ctxWithTimeout, cancel := context.WithTimeout(ctx, dbo.config.TransactionTimeout)
 	defer cancel()
 
-	db, err := GetDB(ctxWithTimeout)
-	if err != nil {
-		return value, err
+	db := dbo.GetDB(ctxWithTimeout)
+	if dbo.cache != nil {
+		db.invalidations = &cacheInvalidations{}
 	}
 
 	db.DB = db.Begin(&sql.TxOptions{})
@@ -131,15 +162,21 @@ func GetTransResult[T any](ctx context.Context, fn func(ctx context.Context, tx
 			log.Debug(ctxWithTimeout, "rollback transaction successfully", log.Err(funcResult.Error))
 
 		}
+		dbo.finishTransaction(ctxWithTimeout, span, start, false, funcResult.Error)
 		return value, funcResult.Error
 	}
 
 	err = db.Commit().Error
 	if err != nil {
+		err = newError(OpCommit, "", start, err)
 		log.Warn(ctxWithTimeout, "commit transaction failed", log.Err(err))
+		dbo.finishTransaction(ctxWithTimeout, span, start, true, err)
 		return value, err
 	}
 
+	dbo.finishTransaction(ctxWithTimeout, span, start, true, nil)
+	db.commitInvalidations(ctx)
+
 	log.Debug(ctxWithTimeout, "commit transaction successfully")
 
 	return funcResult.Result, nil